@@ -0,0 +1,344 @@
+/*
+Copyright 2017 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package holster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/mailgun/holster/clock"
+)
+
+// BackOffCounter tracks back off attempts. All of its methods are safe to call
+// from multiple goroutines, so a single counter can be shared by several
+// workers retrying the same resource.
+type BackOffCounter struct {
+	mutex    sync.Mutex
+	min, max time.Duration
+	factor   float64
+	attempt  int
+	current  time.Duration
+	jitter   float64
+	rand     *rand.Rand
+	// Zero means unlimited attempts
+	maxAttempts  int
+	decorrelated bool
+	constant     bool
+}
+
+func NewBackOff(min, max time.Duration, factor float64) *BackOffCounter {
+	return &BackOffCounter{
+		factor: factor,
+		min:    min,
+		max:    max,
+	}
+}
+
+// NewBackOffWithJitter is like NewBackOff but randomizes each duration returned
+// by Next() within +/- jitter (a factor between 0 and 1) of the computed back
+// off, clamped to [min, max]. This keeps many clients that failed together from
+// retrying in lock step. The jitter uses a rand seeded from the current time,
+// call SetRand() to provide a deterministic one in tests.
+func NewBackOffWithJitter(min, max time.Duration, factor, jitter float64) *BackOffCounter {
+	b := NewBackOff(min, max, factor)
+	b.jitter = jitter
+	b.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	return b
+}
+
+// NewDecorrelatedBackOff returns a back off that implements the "decorrelated
+// jitter" strategy, where each duration returned by Next() is a random value
+// between `base` and three times the previous duration, capped at `cap`.
+// https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+//
+// The rand is seeded from the current time, call SetRand() to provide a
+// deterministic one in tests. BackOff(attempt) is not affected by this strategy.
+func NewDecorrelatedBackOff(base, cap time.Duration) *BackOffCounter {
+	b := NewBackOff(base, cap, 3)
+	b.decorrelated = true
+	b.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	return b
+}
+
+// NewBackOffWithMaxAttempts is like NewBackOff but NextOrDone() reports
+// exhaustion once it has been called `maxAttempts` times since the last Reset().
+func NewBackOffWithMaxAttempts(min, max time.Duration, factor float64, maxAttempts int) *BackOffCounter {
+	b := NewBackOff(min, max, factor)
+	b.maxAttempts = maxAttempts
+	return b
+}
+
+// NewConstantBackOff returns a back off where every call to Next() returns
+// `interval` without growth, for retry policies that want a fixed delay while
+// keeping the same BackOffCounter surface. Reset() does nothing as there is
+// no growth to undo.
+func NewConstantBackOff(interval time.Duration) *BackOffCounter {
+	b := NewBackOff(interval, interval, 1)
+	b.constant = true
+	return b
+}
+
+// Clone returns a new BackOffCounter with the same configuration and the attempts reset
+// to zero, such that a configured back off can be used as a template by independent retry
+// loops. If the back off is randomized the clone gets its own rand seeded from the
+// current time, as a rand can not be shared safely.
+func (b *BackOffCounter) Clone() *BackOffCounter {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c := &BackOffCounter{
+		min:          b.min,
+		max:          b.max,
+		factor:       b.factor,
+		jitter:       b.jitter,
+		maxAttempts:  b.maxAttempts,
+		decorrelated: b.decorrelated,
+		constant:     b.constant,
+	}
+	if b.rand != nil {
+		c.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return c
+}
+
+// SetRand replaces the source of randomness used to jitter back offs
+func (b *BackOffCounter) SetRand(r *rand.Rand) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rand = r
+}
+
+// Next returns the next back off duration based on the number of
+// times Next() was called. Each call to next returns the next factor
+// of back off. Call Reset() to reset the back off attempts to zero.
+func (b *BackOffCounter) Next() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.next()
+}
+
+func (b *BackOffCounter) next() time.Duration {
+	var d time.Duration
+	switch {
+	case b.decorrelated:
+		d = b.nextDecorrelated()
+	case b.jitter > 0 && b.rand != nil:
+		d = b.applyJitter(b.BackOff(b.attempt))
+	default:
+		d = b.BackOff(b.attempt)
+	}
+	b.attempt++
+	b.current = d
+	return d
+}
+
+// NextOrDone is like Next() but returns false once the max attempts given to
+// NewBackOffWithMaxAttempts have been exhausted, the caller should then give up.
+//
+//	for {
+//		if err := doSomething(); err == nil {
+//			break
+//		}
+//		d, ok := b.NextOrDone()
+//		if !ok {
+//			return errors.New("giving up")
+//		}
+//		time.Sleep(d)
+//	}
+func (b *BackOffCounter) NextOrDone() (time.Duration, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.maxAttempts > 0 && b.attempt >= b.maxAttempts {
+		return 0, false
+	}
+	return b.next(), true
+}
+
+// NextCapped is like Next() but also returns true once the back off has reached
+// the max given to the constructor, indicating a sustained failure that callers
+// may want to log or escalate. Jitter does not affect whether the back off is capped.
+func (b *BackOffCounter) NextCapped() (time.Duration, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	capped := b.BackOff(b.attempt) >= b.max
+	d := b.next()
+	if b.decorrelated {
+		capped = d >= b.max
+	}
+	return d, capped
+}
+
+// Attempts returns the number of times Next() was called since the last Reset()
+func (b *BackOffCounter) Attempts() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.attempt
+}
+
+// Current returns the duration last returned by Next(), or zero if Next()
+// was not called since the last Reset()
+func (b *BackOffCounter) Current() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.current
+}
+
+func (b *BackOffCounter) nextDecorrelated() time.Duration {
+	prev := b.current
+	if prev < b.min {
+		prev = b.min
+	}
+	upper := float64(prev) * b.factor
+	d := time.Duration(float64(b.min) + b.rand.Float64()*(upper-float64(b.min)))
+	if d > b.max {
+		return b.max
+	}
+	return d
+}
+
+func (b *BackOffCounter) applyJitter(d time.Duration) time.Duration {
+	delta := b.jitter * float64(d)
+	d = time.Duration(float64(d) - delta + b.rand.Float64()*2*delta)
+	if d > b.max {
+		return b.max
+	}
+	if d < b.min {
+		return b.min
+	}
+	return d
+}
+
+// Wait sleeps for the duration returned by Next(). It returns ctx.Err() if the
+// context is cancelled before the back off elapses, and nil otherwise.
+func (b *BackOffCounter) Wait(ctx context.Context) error {
+	timer := clock.NewTimer(b.Next())
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Do calls `fn` until it returns nil, waiting for the duration returned by Next()
+// after each error, then resets the back off. It returns ctx.Err() if the context
+// is cancelled first, and the last error returned by `fn` once the max attempts
+// given to NewBackOffWithMaxAttempts() are exhausted.
+//
+//	b := holster.NewBackOff(time.Millisecond*500, time.Second*30, 2)
+//	err := b.Do(ctx, func() error {
+//		return registerCampaign()
+//	})
+func (b *BackOffCounter) Do(ctx context.Context, fn func() error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn()
+		if err == nil {
+			b.Reset()
+			return nil
+		}
+
+		d, ok := b.NextOrDone()
+		if !ok {
+			return err
+		}
+		timer := clock.NewTimer(d)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Reset sets the back off attempt counter to zero, it is a no-op for
+// back offs created with NewConstantBackOff()
+func (b *BackOffCounter) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.constant {
+		return
+	}
+	b.attempt = 0
+	b.current = 0
+}
+
+// String summarizes the configuration and current state of the back off for logging
+func (b *BackOffCounter) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return fmt.Sprintf("BackOffCounter{min: %s, max: %s, factor: %g, jitter: %g, attempts: %d, current: %s}",
+		b.min, b.max, b.factor, b.jitter, b.attempt, b.current)
+}
+
+// MarshalJSON marshals the configuration and current state of the back off, such
+// that it can be included in debug endpoints which serialize component state.
+// Durations are marshaled as strings like "1.5s".
+func (b *BackOffCounter) MarshalJSON() ([]byte, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return json.Marshal(struct {
+		Min          string  `json:"min"`
+		Max          string  `json:"max"`
+		Factor       float64 `json:"factor"`
+		Jitter       float64 `json:"jitter"`
+		Attempts     int     `json:"attempts"`
+		MaxAttempts  int     `json:"max_attempts"`
+		Current      string  `json:"current"`
+		Decorrelated bool    `json:"decorrelated"`
+	}{
+		Min:          b.min.String(),
+		Max:          b.max.String(),
+		Factor:       b.factor,
+		Jitter:       b.jitter,
+		Attempts:     b.attempt,
+		MaxAttempts:  b.maxAttempts,
+		Current:      b.current.String(),
+		Decorrelated: b.decorrelated,
+	})
+}
+
+// BackOff calculates the back depending on the attempts provided
+func (b *BackOffCounter) BackOff(attempt int) time.Duration {
+	d := time.Duration(float64(b.min) * math.Pow(b.factor, float64(attempt)))
+	if d > b.max {
+		return b.max
+	}
+	if d < b.min {
+		return b.min
+	}
+	return d
+}
+
+// BackOff is a convenience function which returns a back off duration
+// with a default 300 millisecond minimum back off, a default 30 second
+// maximum back off and a factor of 2, which returns
+// [ 300ms, 600ms, 1.2s, 2.4s, 4.8s, 9.6s, 19.2s, 30s ]
+func BackOff(attempt int) time.Duration {
+	return NewBackOff(time.Millisecond*300, time.Second*30, 2).BackOff(attempt)
+}
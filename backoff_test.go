@@ -1,10 +1,17 @@
 package holster_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/mailgun/holster"
+	"github.com/mailgun/holster/clock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,3 +22,318 @@ func TestBackoffFunc(t *testing.T) {
 	d = holster.BackOff(1)
 	assert.Equal(t, time.Millisecond*600, d)
 }
+
+func TestBackOffJitter(t *testing.T) {
+	min, max := time.Millisecond*100, time.Second
+	b := holster.NewBackOffWithJitter(min, max, 2, 0.5)
+	b.SetRand(rand.New(rand.NewSource(42)))
+
+	for i := 0; i < 1000; i++ {
+		attempt := i % 6
+		if attempt == 0 {
+			b.Reset()
+		}
+		want := b.BackOff(attempt)
+		d := b.Next()
+
+		assert.True(t, d >= min, "%s below min", d)
+		assert.True(t, d <= max, "%s above max", d)
+		assert.True(t, d >= want/2, "%s below jittered bound of %s", d, want)
+		assert.True(t, d <= want+want/2, "%s above jittered bound of %s", d, want)
+	}
+}
+
+func TestBackOffJitterSpread(t *testing.T) {
+	b := holster.NewBackOffWithJitter(time.Millisecond, time.Hour, 2, 0.5)
+	b.SetRand(rand.New(rand.NewSource(42)))
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 10; i++ {
+		b.Reset()
+		seen[b.Next()] = true
+	}
+	assert.True(t, len(seen) > 1, "jitter produced identical back offs")
+}
+
+func TestBackOffWait(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	b := holster.NewBackOff(time.Second, time.Minute, 2)
+	errs := make(chan error)
+	go func() {
+		errs <- b.Wait(context.Background())
+	}()
+	assert.True(t, clock.Wait4Scheduled(1, time.Second))
+
+	clock.Advance(time.Second)
+	assert.Nil(t, <-errs)
+}
+
+func TestBackOffWaitCancelled(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	b := holster.NewBackOff(time.Second, time.Minute, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		errs <- b.Wait(ctx)
+	}()
+	assert.True(t, clock.Wait4Scheduled(1, time.Second))
+
+	clock.Advance(time.Millisecond * 500)
+	cancel()
+	assert.Equal(t, context.Canceled, <-errs)
+}
+
+func TestBackOffDo(t *testing.T) {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+
+	b := holster.NewBackOff(time.Second, time.Minute, 2)
+	var calls int32
+	errs := make(chan error)
+	go func() {
+		errs <- b.Do(context.Background(), func() error {
+			if atomic.AddInt32(&calls, 1) <= 2 {
+				return errors.New("failed")
+			}
+			return nil
+		})
+	}()
+
+	// Retried with a growing back off
+	for _, want := range []time.Duration{time.Second, time.Second * 2} {
+		assert.True(t, clock.Wait4Scheduled(1, time.Second))
+		assert.Equal(t, want, b.Current())
+		clock.Advance(want)
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// Reset after success
+	assert.Equal(t, 0, b.Attempts())
+	assert.Equal(t, time.Duration(0), b.Current())
+}
+
+func TestBackOffDoCancelled(t *testing.T) {
+	b := holster.NewBackOff(time.Minute, time.Hour, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		errs <- b.Do(ctx, func() error {
+			return errors.New("failed")
+		})
+	}()
+	cancel()
+	assert.Equal(t, context.Canceled, <-errs)
+}
+
+func TestBackOffDoMaxAttempts(t *testing.T) {
+	b := holster.NewBackOffWithMaxAttempts(time.Millisecond, time.Millisecond, 1, 2)
+	var calls int
+	err := b.Do(context.Background(), func() error {
+		calls++
+		return errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, 3, calls)
+}
+
+func TestBackOffMaxAttempts(t *testing.T) {
+	b := holster.NewBackOffWithMaxAttempts(time.Millisecond, time.Second, 2, 3)
+
+	for _, want := range []time.Duration{time.Millisecond, time.Millisecond * 2, time.Millisecond * 4} {
+		d, ok := b.NextOrDone()
+		assert.True(t, ok)
+		assert.Equal(t, want, d)
+	}
+	d, ok := b.NextOrDone()
+	assert.False(t, ok)
+	assert.Equal(t, time.Duration(0), d)
+
+	b.Reset()
+	d, ok = b.NextOrDone()
+	assert.True(t, ok)
+	assert.Equal(t, time.Millisecond, d)
+}
+
+func TestBackOffUnlimitedAttempts(t *testing.T) {
+	b := holster.NewBackOff(time.Millisecond, time.Second, 2)
+	for i := 0; i < 100; i++ {
+		_, ok := b.NextOrDone()
+		assert.True(t, ok)
+	}
+}
+
+func TestBackOffNextCapped(t *testing.T) {
+	b := holster.NewBackOff(time.Millisecond*100, time.Millisecond*500, 2)
+
+	for _, want := range []struct {
+		d      time.Duration
+		capped bool
+	}{
+		{time.Millisecond * 100, false},
+		{time.Millisecond * 200, false},
+		{time.Millisecond * 400, false},
+		{time.Millisecond * 500, true},
+		{time.Millisecond * 500, true},
+	} {
+		d, capped := b.NextCapped()
+		assert.Equal(t, want.d, d)
+		assert.Equal(t, want.capped, capped)
+	}
+
+	b.Reset()
+	d, capped := b.NextCapped()
+	assert.Equal(t, time.Millisecond*100, d)
+	assert.False(t, capped)
+}
+
+func TestBackOffAttemptsAndCurrent(t *testing.T) {
+	b := holster.NewBackOff(time.Millisecond, time.Second, 2)
+	assert.Equal(t, 0, b.Attempts())
+	assert.Equal(t, time.Duration(0), b.Current())
+
+	b.Next()
+	assert.Equal(t, 1, b.Attempts())
+	assert.Equal(t, time.Millisecond, b.Current())
+
+	b.Next()
+	b.Next()
+	assert.Equal(t, 3, b.Attempts())
+	assert.Equal(t, time.Millisecond*4, b.Current())
+
+	b.Reset()
+	assert.Equal(t, 0, b.Attempts())
+	assert.Equal(t, time.Duration(0), b.Current())
+}
+
+func TestDecorrelatedBackOff(t *testing.T) {
+	base, cap := time.Millisecond*100, time.Second*10
+	b := holster.NewDecorrelatedBackOff(base, cap)
+	b.SetRand(rand.New(rand.NewSource(42)))
+
+	prev := base
+	for i := 0; i < 1000; i++ {
+		if i%20 == 0 {
+			b.Reset()
+			prev = base
+		}
+		d := b.Next()
+
+		assert.True(t, d >= base, "%s below base", d)
+		assert.True(t, d <= cap, "%s above cap", d)
+		// Each value is drawn from [base, prev*3]
+		assert.True(t, d <= prev*3, "%s above 3x previous %s", d, prev)
+		assert.Equal(t, d, b.Current())
+		prev = d
+	}
+}
+
+func TestDecorrelatedBackOffSeeded(t *testing.T) {
+	run := func() []time.Duration {
+		b := holster.NewDecorrelatedBackOff(time.Millisecond*100, time.Second*10)
+		b.SetRand(rand.New(rand.NewSource(7)))
+		var result []time.Duration
+		for i := 0; i < 10; i++ {
+			result = append(result, b.Next())
+		}
+		return result
+	}
+	assert.Equal(t, run(), run())
+}
+
+func TestConstantBackOff(t *testing.T) {
+	b := holster.NewConstantBackOff(time.Millisecond * 250)
+
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, time.Millisecond*250, b.Next())
+	}
+	assert.Equal(t, 100, b.Attempts())
+
+	b.Reset()
+	assert.Equal(t, 100, b.Attempts())
+	assert.Equal(t, time.Millisecond*250, b.Next())
+}
+
+func TestBackOffString(t *testing.T) {
+	b := holster.NewBackOff(time.Millisecond*300, time.Second*30, 2)
+	b.Next()
+	b.Next()
+	assert.Equal(t, "BackOffCounter{min: 300ms, max: 30s, factor: 2, jitter: 0, attempts: 2, current: 600ms}", b.String())
+}
+
+func TestBackOffMarshalJSON(t *testing.T) {
+	b := holster.NewBackOffWithMaxAttempts(time.Millisecond*300, time.Second*30, 2, 5)
+	b.Next()
+	b.Next()
+	b.Next()
+
+	out, err := json.Marshal(b)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"min": "300ms",
+		"max": "30s",
+		"factor": 2,
+		"jitter": 0,
+		"attempts": 3,
+		"max_attempts": 5,
+		"current": "1.2s",
+		"decorrelated": false
+	}`, string(out))
+}
+
+func TestBackOffClone(t *testing.T) {
+	template := holster.NewBackOffWithMaxAttempts(time.Millisecond*100, time.Second, 2, 3)
+	template.Next()
+
+	clone := template.Clone()
+	assert.Equal(t, 0, clone.Attempts())
+	assert.Equal(t, time.Duration(0), clone.Current())
+	assert.Equal(t, "BackOffCounter{min: 100ms, max: 1s, factor: 2, jitter: 0, attempts: 0, current: 0s}", clone.String())
+
+	// Attempts are independent
+	assert.Equal(t, time.Millisecond*100, clone.Next())
+	assert.Equal(t, time.Millisecond*200, clone.Next())
+	assert.Equal(t, 2, clone.Attempts())
+	assert.Equal(t, 1, template.Attempts())
+
+	// Including the max attempts
+	clone.Next()
+	_, ok := clone.NextOrDone()
+	assert.False(t, ok)
+	_, ok = template.NextOrDone()
+	assert.True(t, ok)
+}
+
+// Run with -race to detect unsynchronized access
+func TestBackOffConcurrent(t *testing.T) {
+	for _, b := range []*holster.BackOffCounter{
+		holster.NewBackOff(time.Millisecond, time.Second, 2),
+		holster.NewBackOffWithJitter(time.Millisecond, time.Second, 2, 0.5),
+		holster.NewBackOffWithMaxAttempts(time.Millisecond, time.Second, 2, 500),
+		holster.NewDecorrelatedBackOff(time.Millisecond, time.Second),
+	} {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					b.Next()
+					b.NextOrDone()
+					b.Attempts()
+					b.Current()
+				}
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b.Reset()
+				b.SetRand(rand.New(rand.NewSource(int64(j))))
+			}
+		}()
+		wg.Wait()
+	}
+}
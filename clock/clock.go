@@ -47,16 +47,26 @@ func Realtime() Clock {
 	return realtime
 }
 
+// NewRealClock returns a Clock that forwards all calls to the SDK's time
+// package. Libraries that accept a Clock should default to it, so tests can
+// substitute a clock created by NewFrozenClock without touching global state.
+func NewRealClock() Clock {
+	return &systemTime{}
+}
+
 // Makes the deterministic time move forward by the specified duration, firing
 // timers along the way in the natural order. It returns how much time has
 // passed since it was frozen. So you can assert on the return value in tests
 // to make it explicit where you stand on the deterministic time scale.
 func Advance(d time.Duration) time.Duration {
-	ft, ok := provider.(*frozenTime)
-	if !ok {
+	switch p := provider.(type) {
+	case *frozenTime:
+		p.advance(d)
+	case *FrozenClock:
+		p.advance(d)
+	default:
 		panic("Freeze time first!")
 	}
-	ft.advance(d)
 	return Now().UTC().Sub(frozenAt)
 }
 
@@ -72,6 +82,22 @@ func Now() time.Time {
 	return provider.Now()
 }
 
+// NowUnix returns Now() as a Unix time in seconds. It is not named Unix as that
+// is taken by the counterpart of time.Unix.
+func NowUnix() int64 {
+	return provider.Now().Unix()
+}
+
+// NowUnixMilli returns Now() as a Unix time in milliseconds.
+func NowUnixMilli() int64 {
+	return provider.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// NowUnixNano returns Now() as a Unix time in nanoseconds.
+func NowUnixNano() int64 {
+	return provider.Now().UnixNano()
+}
+
 // Sleep see time.Sleep.
 func Sleep(d time.Duration) {
 	provider.Sleep(d)
@@ -103,6 +129,7 @@ func AfterFunc(d time.Duration, f func()) Timer {
 type Ticker interface {
 	C() <-chan time.Time
 	Stop()
+	Reset(d time.Duration)
 }
 
 // NewTicker see time.Ticker.
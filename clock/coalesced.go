@@ -0,0 +1,69 @@
+package clock
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CoalescedClock is a Clock for hot paths that call Now() on every request. It
+// caches the current time and refreshes it from a background goroutine once per
+// resolution, so Now() is an atomic load instead of a call to time.Now().
+//
+// The trade-off is accuracy: Now() lags the real time by up to the resolution,
+// plus any delay the Go scheduler adds before the refresh runs, and successive
+// calls within a resolution return the same time. The cached time carries no
+// monotonic clock reading, so durations measured with it are subject to wall
+// clock adjustments. Timers, tickers and Sleep are not affected and forward to
+// the SDK's time package.
+type CoalescedClock struct {
+	systemTime
+	now      atomic.Value
+	stopped  int32
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewCoalescedClock returns a CoalescedClock that refreshes its time once per
+// resolution. Stop must be called to release the background goroutine once the
+// clock is no longer used.
+//
+//	c := clock.NewCoalescedClock(clock.Millisecond)
+//	defer c.Stop()
+//
+//	received := c.Now()
+func NewCoalescedClock(resolution time.Duration) *CoalescedClock {
+	c := &CoalescedClock{done: make(chan struct{})}
+	c.now.Store(time.Now().Round(0))
+
+	ticker := time.NewTicker(resolution)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				c.now.Store(now.Round(0))
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// Now returns the cached time, which is at most resolution behind the real time.
+// After Stop is called it returns time.Now().
+func (c *CoalescedClock) Now() time.Time {
+	if atomic.LoadInt32(&c.stopped) == 1 {
+		return time.Now()
+	}
+	return c.now.Load().(time.Time)
+}
+
+// Stop stops refreshing the cached time. It is safe to call more than once.
+func (c *CoalescedClock) Stop() {
+	c.stopOnce.Do(func() {
+		atomic.StoreInt32(&c.stopped, 1)
+		close(c.done)
+	})
+}
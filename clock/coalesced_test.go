@@ -0,0 +1,59 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type CoalescedSuite struct{}
+
+var _ = Suite(&CoalescedSuite{})
+
+func (s *CoalescedSuite) TestNowWithinResolution(c *C) {
+	const resolution = 10 * time.Millisecond
+	// Allow for the scheduler delaying the refresh on a busy machine
+	const bound = 5 * resolution
+
+	clk := NewCoalescedClock(resolution)
+	defer clk.Stop()
+
+	prev := clk.Now()
+	for i := 0; i < 50; i++ {
+		now := clk.Now()
+		actual := time.Now()
+		c.Assert(now.After(actual), Equals, false)
+		c.Assert(actual.Sub(now) <= bound, Equals, true, Commentf("lag %s", actual.Sub(now)))
+		c.Assert(now.Before(prev), Equals, false)
+		prev = now
+		time.Sleep(time.Millisecond * 3)
+	}
+}
+
+func (s *CoalescedSuite) TestStop(c *C) {
+	clk := NewCoalescedClock(time.Hour)
+	cached := clk.Now()
+	time.Sleep(time.Millisecond * 5)
+	c.Assert(clk.Now(), Equals, cached)
+
+	clk.Stop()
+	clk.Stop()
+	c.Assert(clk.Now().After(cached), Equals, true)
+}
+
+func BenchmarkCoalescedClockNow(b *testing.B) {
+	clk := NewCoalescedClock(time.Millisecond)
+	defer clk.Stop()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		clk.Now()
+	}
+}
+
+func BenchmarkTimeNow(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		time.Now()
+	}
+}
@@ -0,0 +1,70 @@
+package clock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithDeadline is like context.WithDeadline but the deadline is measured by the
+// clock, so while time is frozen the context is only cancelled with
+// context.DeadlineExceeded once Advance moves the clock past the deadline.
+func WithDeadline(parent context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	if provider == realtime {
+		return context.WithDeadline(parent, deadline)
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	c := &deadlineCtx{Context: ctx, deadline: deadline}
+
+	d := deadline.Sub(provider.Now())
+	if d <= 0 {
+		c.expire(cancel)
+		return c, cancel
+	}
+
+	timer := provider.AfterFunc(d, func() {
+		c.expire(cancel)
+	})
+	return c, func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+// WithTimeout returns WithDeadline(parent, Now().Add(timeout)).
+func WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return WithDeadline(parent, Now().Add(timeout))
+}
+
+// deadlineCtx reports the deadline and context.DeadlineExceeded once it expires,
+// which a context cancelled with a CancelFunc can not.
+type deadlineCtx struct {
+	context.Context
+	deadline time.Time
+
+	mu  sync.Mutex
+	err error
+}
+
+func (c *deadlineCtx) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *deadlineCtx) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return c.Context.Err()
+}
+
+func (c *deadlineCtx) expire(cancel context.CancelFunc) {
+	c.mu.Lock()
+	if c.Context.Err() == nil {
+		c.err = context.DeadlineExceeded
+	}
+	c.mu.Unlock()
+	cancel()
+}
@@ -0,0 +1,79 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithTimeoutFrozen(t *testing.T) {
+	defer Freeze(Now()).Unfreeze()
+
+	ctx, cancel := WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	if !ok || !deadline.Equal(Now().Add(time.Second)) {
+		t.Fatalf("unexpected deadline %s", deadline)
+	}
+
+	// Real time passing does not expire the context
+	time.Sleep(10 * time.Millisecond)
+	Advance(999 * time.Millisecond)
+	if ctx.Err() != nil {
+		t.Fatalf("expected no error before the deadline, got %v", ctx.Err())
+	}
+
+	Advance(time.Millisecond)
+	select {
+	case <-ctx.Done():
+	default:
+		t.Fatal("expected the context to be done after the deadline")
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, ctx.Err())
+	}
+
+	// Derived contexts inherit the error
+	child, cancelChild := context.WithCancel(ctx)
+	defer cancelChild()
+	<-child.Done()
+	if child.Err() != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, child.Err())
+	}
+}
+
+func TestWithDeadlineFrozenCancel(t *testing.T) {
+	defer Freeze(Now()).Unfreeze()
+
+	ctx, cancel := WithDeadline(context.Background(), Now().Add(time.Second))
+	cancel()
+	if ctx.Err() != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, ctx.Err())
+	}
+
+	// Advancing past the deadline does not change the error
+	Advance(time.Second)
+	if ctx.Err() != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, ctx.Err())
+	}
+}
+
+func TestWithDeadlineFrozenPast(t *testing.T) {
+	defer Freeze(Now()).Unfreeze()
+
+	ctx, cancel := WithDeadline(context.Background(), Now().Add(-time.Second))
+	defer cancel()
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, ctx.Err())
+	}
+}
+
+func TestWithTimeoutRealtime(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, ctx.Err())
+	}
+}
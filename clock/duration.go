@@ -2,10 +2,115 @@ package clock
 
 import (
 	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
+var (
+	longUnits = map[string]Duration{
+		"d": 24 * Hour,
+		"w": 7 * 24 * Hour,
+	}
+	stdUnits = map[string]bool{
+		"ns": true, "us": true, "µs": true, "μs": true,
+		"ms": true, "s": true, "m": true, "h": true,
+	}
+)
+
+// ParseDuration is like time.ParseDuration but also understands the `d` (24h)
+// and `w` (168h) units, which compose with the standard ones, e.g. "1d12h" or
+// "2w3d". Days are always 24 hours long regardless of daylight saving changes.
+func ParseDuration(s string) (Duration, error) {
+	if !strings.ContainsAny(s, "dw") {
+		return time.ParseDuration(s)
+	}
+	orig := s
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	isNum := func(r rune) bool { return r == '.' || ('0' <= r && r <= '9') }
+
+	var long Duration
+	var std string
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool { return !isNum(r) })
+		if i <= 0 {
+			return 0, errors.Errorf("clock: invalid duration %q", orig)
+		}
+		j := strings.IndexFunc(s[i:], isNum)
+		if j < 0 {
+			j = len(s) - i
+		}
+		num, unit := s[:i], s[i:i+j]
+		s = s[i+j:]
+
+		if stdUnits[unit] {
+			std += num + unit
+			continue
+		}
+		unitDuration, ok := longUnits[unit]
+		if !ok {
+			return 0, errors.Errorf("clock: unknown unit %q in duration %q", unit, orig)
+		}
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, errors.Errorf("clock: invalid duration %q", orig)
+		}
+		f = f*float64(unitDuration) + float64(long)
+		if f > math.MaxInt64 {
+			return 0, errors.Errorf("clock: invalid duration %q", orig)
+		}
+		long = Duration(f)
+	}
+
+	var d Duration
+	if std != "" {
+		var err error
+		if d, err = time.ParseDuration(std); err != nil || d > math.MaxInt64-long {
+			return 0, errors.Errorf("clock: invalid duration %q", orig)
+		}
+	}
+	if neg {
+		return -(long + d), nil
+	}
+	return long + d, nil
+}
+
+// FormatDuration is the inverse of ParseDuration, it renders the duration with the
+// largest units possible omitting those which are zero, e.g. 90m as "1h30m" and 36h as
+// "1d12h". Parts smaller than a minute are formatted by Duration.String().
+func FormatDuration(d Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+	var b strings.Builder
+	u := uint64(d)
+	if d < 0 {
+		b.WriteByte('-')
+		u = -u
+	}
+	for _, unit := range []struct {
+		suffix   string
+		duration Duration
+	}{{"w", 7 * 24 * Hour}, {"d", 24 * Hour}, {"h", Hour}, {"m", Minute}} {
+		if n := u / uint64(unit.duration); n > 0 {
+			b.WriteString(strconv.FormatUint(n, 10))
+			b.WriteString(unit.suffix)
+			u -= n * uint64(unit.duration)
+		}
+	}
+	if u > 0 {
+		b.WriteString(Duration(u).String())
+	}
+	return b.String()
+}
+
 type DurationJSON struct {
 	Duration Duration
 }
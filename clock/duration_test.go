@@ -2,6 +2,7 @@ package clock_test
 
 import (
 	"encoding/json"
+	"math"
 	"testing"
 
 	"github.com/mailgun/holster/clock"
@@ -77,3 +78,100 @@ func (s *DurationSuite) TestMarshalling() {
 	s.Equal(d, decoded)
 	s.Equal("42s", decoded.String())
 }
+
+func (s *DurationSuite) TestParseDuration() {
+	for _, tc := range []struct {
+		in  string
+		out clock.Duration
+	}{{
+		in:  "90m",
+		out: 90 * clock.Minute,
+	}, {
+		in:  "1d",
+		out: 24 * clock.Hour,
+	}, {
+		in:  "1d12h",
+		out: 36 * clock.Hour,
+	}, {
+		in:  "2w3d",
+		out: 17 * 24 * clock.Hour,
+	}, {
+		in:  "1.5d30m500ms",
+		out: 36*clock.Hour + 30*clock.Minute + 500*clock.Millisecond,
+	}, {
+		in:  "-1w",
+		out: -7 * 24 * clock.Hour,
+	}} {
+		d, err := clock.ParseDuration(tc.in)
+		s.Nil(err, tc.in)
+		s.Equal(tc.out, d, tc.in)
+	}
+}
+
+func (s *DurationSuite) TestFormatDuration() {
+	for _, tc := range []struct {
+		in         string
+		normalized string
+	}{
+		{in: "0", normalized: "0s"},
+		{in: "90m", normalized: "1h30m"},
+		{in: "36h", normalized: "1d12h"},
+		{in: "1d12h", normalized: "1d12h"},
+		{in: "2w3d", normalized: "2w3d"},
+		{in: "14d", normalized: "2w"},
+		{in: "1d0h30m", normalized: "1d30m"},
+		{in: "1.5d30m500ms", normalized: "1d12h30m500ms"},
+		{in: "90.5s", normalized: "1m30.5s"},
+		{in: "750us", normalized: "750µs"},
+		{in: "-25h", normalized: "-1d1h"},
+		{in: "-1w", normalized: "-1w"},
+	} {
+		d, err := clock.ParseDuration(tc.in)
+		s.Nil(err, tc.in)
+		s.Equal(tc.normalized, clock.FormatDuration(d), tc.in)
+
+		// Formatting is stable across round trips
+		d2, err := clock.ParseDuration(clock.FormatDuration(d))
+		s.Nil(err, tc.in)
+		s.Equal(d, d2, tc.in)
+	}
+	// The minimum duration can not be negated
+	s.Equal("-15250w1d23h47m16.854775808s", clock.FormatDuration(math.MinInt64))
+}
+
+func (s *DurationSuite) TestParseDurationError() {
+	for _, tc := range []struct {
+		in     string
+		errMsg string
+	}{{
+		in:     "1d2x",
+		errMsg: `clock: unknown unit "x" in duration "1d2x"`,
+	}, {
+		in:     "1d1mo",
+		errMsg: `clock: unknown unit "mo" in duration "1d1mo"`,
+	}, {
+		in:     "d",
+		errMsg: `clock: invalid duration "d"`,
+	}, {
+		in:     "1w2",
+		errMsg: `clock: invalid duration "1w2"`,
+	}, {
+		in:     "1..2d",
+		errMsg: `clock: invalid duration "1..2d"`,
+	}, {
+		in:     "100000000w",
+		errMsg: `clock: invalid duration "100000000w"`,
+	}} {
+		_, err := clock.ParseDuration(tc.in)
+		s.EqualError(err, tc.errMsg, tc.in)
+	}
+}
+
+func (s *DurationSuite) TestUnmarshalDays() {
+	var withDuration struct {
+		Foo clock.DurationJSON `json:"foo"`
+	}
+	err := json.Unmarshal([]byte(`{"foo": "7d"}`), &withDuration)
+	s.Nil(err)
+	s.Equal(7*24*clock.Hour, withDuration.Foo.Duration)
+}
@@ -1,6 +1,7 @@
 package clock
 
 import (
+	"container/heap"
 	"sync"
 	"time"
 
@@ -10,12 +11,34 @@ import (
 type frozenTime struct {
 	mu     sync.Mutex
 	now    time.Time
-	timers []*frozenTimer
+	timers timerHeap
+	// Incremented for each started timer to order timers with equal deadlines
+	seq    uint64
 	waiter *waiter
 }
 
+// FrozenClock is a Clock that is independent of the package-global Freeze. Its
+// time only moves forward when Advance is called, firing its timers in the
+// natural order.
+type FrozenClock struct {
+	frozenTime
+	start time.Time
+}
+
+// NewFrozenClock returns a FrozenClock set to `start`.
+func NewFrozenClock(start time.Time) *FrozenClock {
+	return &FrozenClock{frozenTime: frozenTime{now: start}, start: start}
+}
+
+// Advance makes the clock move forward by the specified duration, firing timers
+// along the way. It returns how much time has passed since the clock was created.
+func (fc *FrozenClock) Advance(d time.Duration) time.Duration {
+	fc.advance(d)
+	return fc.Now().Sub(fc.start)
+}
+
 type waiter struct {
-	count int
+	count    int
 	signalCh chan struct{}
 }
 
@@ -70,7 +93,6 @@ func (ft *frozenTime) advance(d time.Duration) {
 		// as stopped.
 		if t.interval != 0 {
 			t.when = t.when.Add(t.interval)
-			t.stopped = false
 			ft.unlockedStartTimer(t)
 		}
 		// If a function is associated with the timer then call it, but make
@@ -90,21 +112,16 @@ func (ft *frozenTime) advance(d time.Duration) {
 func (ft *frozenTime) stopTimer(t *frozenTimer) bool {
 	ft.mu.Lock()
 	defer ft.mu.Unlock()
+	return ft.unlockedStopTimer(t)
+}
 
-	if t.stopped {
+func (ft *frozenTime) unlockedStopTimer(t *frozenTimer) bool {
+	if t.stopped || t.index < 0 {
 		return false
 	}
-	for i, curr := range ft.timers {
-		if curr == t {
-			t.stopped = true
-			copy(ft.timers[i:], ft.timers[i+1:])
-			lastIdx := len(ft.timers) - 1
-			ft.timers[lastIdx] = nil
-			ft.timers = ft.timers[:lastIdx]
-			return true
-		}
-	}
-	return false
+	heap.Remove(&ft.timers, t.index)
+	t.stopped = true
+	return true
 }
 
 func (ft *frozenTime) nextExpired() *frozenTimer {
@@ -115,10 +132,7 @@ func (ft *frozenTime) nextExpired() *frozenTimer {
 	if ft.now.Before(t.when) {
 		return nil
 	}
-	copy(ft.timers, ft.timers[1:])
-	lastIdx := len(ft.timers) - 1
-	ft.timers[lastIdx] = nil
-	ft.timers = ft.timers[:lastIdx]
+	heap.Pop(&ft.timers)
 	t.stopped = true
 	return t
 }
@@ -128,26 +142,81 @@ func (ft *frozenTime) startTimer(t *frozenTimer) {
 	defer ft.mu.Unlock()
 
 	ft.unlockedStartTimer(t)
+	ft.signalWaiter()
+}
+
+// resetTimer stops the timer and starts it again to fire after `d`, changing its
+// interval if `interval` is not zero. It returns true if the timer was active.
+func (ft *frozenTime) resetTimer(t *frozenTimer, d, interval time.Duration) bool {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	active := ft.unlockedStopTimer(t)
+	if interval != 0 {
+		t.interval = interval
+	}
+	t.when = ft.now.Add(d)
+	ft.unlockedStartTimer(t)
+	ft.signalWaiter()
+	return active
+}
+
+func (ft *frozenTime) unlockedStartTimer(t *frozenTimer) {
+	ft.seq++
+	t.seq = ft.seq
+	t.stopped = false
+	heap.Push(&ft.timers, t)
+}
 
+// signalWaiter wakes Wait4Scheduled once enough timers are scheduled, the
+// caller must hold the lock
+func (ft *frozenTime) signalWaiter() {
 	if ft.waiter == nil {
 		return
 	}
-	if len(ft.timers) >= ft.waiter.count {
+	if len(ft.timers) < ft.waiter.count {
+		return
+	}
+	select {
+	case <-ft.waiter.signalCh:
+	default:
 		close(ft.waiter.signalCh)
 	}
 }
 
-func (ft *frozenTime) unlockedStartTimer(t *frozenTimer) {
-	pos := 0
-	for _, curr := range ft.timers {
-		if t.when.Before(curr.when) {
-			break
-		}
-		pos++
+// timerHeap is a min-heap of pending timers ordered by deadline, timers
+// with equal deadlines fire in the order they were started.
+type timerHeap []*frozenTimer
+
+func (h timerHeap) Len() int { return len(h) }
+
+func (h timerHeap) Less(i, j int) bool {
+	if h[i].when.Equal(h[j].when) {
+		return h[i].seq < h[j].seq
 	}
-	ft.timers = append(ft.timers, nil)
-	copy(ft.timers[pos+1:], ft.timers[pos:])
-	ft.timers[pos] = t
+	return h[i].when.Before(h[j].when)
+}
+
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *timerHeap) Push(x interface{}) {
+	t := x.(*frozenTimer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *timerHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+	*h = old[:n-1]
+	return t
 }
 
 type frozenTimer struct {
@@ -155,8 +224,11 @@ type frozenTimer struct {
 	when     time.Time
 	interval time.Duration
 	stopped  bool
-	c        chan time.Time
-	f        func()
+	// Position in frozenTime.timers, or -1 if not scheduled
+	index int
+	seq   uint64
+	c     chan time.Time
+	f     func()
 }
 
 func (t *frozenTimer) C() <-chan time.Time {
@@ -168,10 +240,7 @@ func (t *frozenTimer) Stop() bool {
 }
 
 func (t *frozenTimer) Reset(d time.Duration) bool {
-	active := t.ft.stopTimer(t)
-	t.when = t.ft.Now().Add(d)
-	t.ft.startTimer(t)
-	return active
+	return t.ft.resetTimer(t, d, 0)
 }
 
 type frozenTicker struct {
@@ -186,6 +255,13 @@ func (t *frozenTicker) Stop() {
 	t.t.Stop()
 }
 
+func (t *frozenTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic(errors.New("non-positive interval for Ticker.Reset"))
+	}
+	t.t.ft.resetTimer(t.t, d, d)
+}
+
 func (ft *frozenTime) NewTicker(d time.Duration) Ticker {
 	if d <= 0 {
 		panic(errors.New("non-positive interval for NewTicker"))
@@ -2,6 +2,7 @@ package clock
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -41,6 +42,26 @@ func (s *FrozenSuite) TestAdvanceNow(c *C) {
 	c.Assert(Now(), Equals, s.epoch.Add(74*time.Millisecond))
 }
 
+// Advancing a frozen clock fires timers without sleeping in real time.
+func (s *FrozenSuite) TestAdvanceNoRealSleep(c *C) {
+	start := Realtime().Now()
+	done := make(chan struct{})
+	go func() {
+		Sleep(time.Hour)
+		close(done)
+	}()
+	c.Assert(Wait4Scheduled(1, time.Second), Equals, true)
+
+	c.Assert(Advance(time.Hour), Equals, time.Hour)
+	select {
+	case <-done:
+	case <-Realtime().After(time.Second):
+		c.Fatal("Sleep did not return after Advance")
+	}
+	c.Assert(Now(), Equals, s.epoch.Add(time.Hour))
+	c.Assert(Realtime().Now().Sub(start) < time.Second, Equals, true)
+}
+
 func (s *FrozenSuite) TestSleep(c *C) {
 	hits := make(chan int, 100)
 
@@ -80,14 +101,8 @@ func (s *FrozenSuite) TestSleep(c *C) {
 		for _, delay := range delays {
 			go tc.fn(delay)
 		}
-		// Spin-wait for all goroutines to fall asleep.
-		ft := provider.(*frozenTime)
-		for {
-			if len(ft.timers) == len(delays) {
-				break
-			}
-			time.Sleep(10 * time.Millisecond)
-		}
+		// Wait for all goroutines to fall asleep.
+		c.Assert(Wait4Scheduled(len(delays), time.Second), Equals, true)
 
 		runningMs := 0
 		for i, delayMs := range []int{5, 60, 90, 100, 131, 999} {
@@ -175,6 +190,38 @@ func (s *FrozenSuite) TestReset(c *C) {
 	c.Assert(hits, DeepEquals, []int{3, 1, 2})
 }
 
+// A timer reset more than once is scheduled only once, and is removed by Stop.
+func (s *FrozenSuite) TestResetTwiceThenStop(c *C) {
+	t1 := NewTimer(100)
+	t2 := NewTimer(100)
+
+	t1.Reset(50)
+	t1.Reset(60)
+	t2.Reset(70)
+	t2.Reset(80)
+	c.Assert(len(provider.(*frozenTime).timers), Equals, 2)
+
+	c.Assert(t1.Stop(), Equals, true)
+	c.Assert(t2.Stop(), Equals, true)
+	c.Assert(len(provider.(*frozenTime).timers), Equals, 0)
+
+	Advance(200)
+	assertNotFired(c, t1.C())
+	assertNotFired(c, t2.C())
+}
+
+func (s *FrozenSuite) TestTickerResetTwiceThenStop(c *C) {
+	t := NewTicker(100)
+
+	t.Reset(30)
+	t.Reset(40)
+	c.Assert(len(provider.(*frozenTime).timers), Equals, 1)
+
+	t.Stop()
+	Advance(200)
+	assertNotFired(c, t.C())
+}
+
 // Reset to the same time just puts the timer at the end of the trigger list
 // for the date.
 func (s *FrozenSuite) TestResetSame(c *C) {
@@ -217,6 +264,27 @@ func (s *FrozenSuite) TestTicker(c *C) {
 	assertNotFired(c, t.C())
 }
 
+func (s *FrozenSuite) TestTickerReset(c *C) {
+	t := NewTicker(100)
+
+	Advance(50)
+	t.Reset(30)
+	Advance(29)
+	assertNotFired(c, t.C())
+	Advance(1)
+	c.Assert(s.epoch.Add(80), Equals, <-t.C())
+	Advance(30)
+	c.Assert(s.epoch.Add(110), Equals, <-t.C())
+
+	// A stopped ticker starts ticking again when reset
+	t.Stop()
+	Advance(100)
+	assertNotFired(c, t.C())
+	t.Reset(10)
+	Advance(10)
+	c.Assert(s.epoch.Add(220), Equals, <-t.C())
+}
+
 func (s *FrozenSuite) TestTickerZero(c *C) {
 	defer func() {
 		recover()
@@ -301,6 +369,99 @@ func (s *FrozenSuite) TestUntil(c *C) {
 	c.Assert(Until(Now().Add(-Millisecond)), Equals, -Millisecond)
 }
 
+// Timers scheduled concurrently fire in deadline order on a single Advance,
+// and timers with equal deadlines fire in the order they were scheduled.
+func (s *FrozenSuite) TestAdvanceWakeupOrder(c *C) {
+	delays := []int{70, 10, 50, 30, 90, 20, 80, 40, 60, 30}
+	var mu sync.Mutex
+	var fired []int
+	var scheduled, woken sync.WaitGroup
+
+	for _, delay := range delays {
+		delay := delay
+		scheduled.Add(2)
+		woken.Add(1)
+		go func() {
+			defer scheduled.Done()
+			AfterFunc(Duration(delay)*Millisecond, func() {
+				mu.Lock()
+				fired = append(fired, delay)
+				mu.Unlock()
+			})
+		}()
+		go func() {
+			defer woken.Done()
+			scheduled.Done()
+			Sleep(Duration(delay) * Millisecond)
+		}()
+	}
+	scheduled.Wait()
+	c.Assert(Wait4Scheduled(len(delays)*2, Second), Equals, true)
+
+	// When
+	Advance(100 * Millisecond)
+
+	// Then
+	woken.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(fired, DeepEquals, []int{10, 20, 30, 30, 40, 50, 60, 70, 80, 90})
+}
+
+// Since and Until only reflect virtual advances of a frozen clock.
+func (s *FrozenSuite) TestSinceUntilVirtualTime(c *C) {
+	start := Now()
+	deadline := start.Add(5 * Second)
+
+	time.Sleep(10 * Millisecond)
+	c.Assert(Since(start), Equals, Duration(0))
+	c.Assert(Until(deadline), Equals, 5*Second)
+
+	Advance(3 * Second)
+	c.Assert(Since(start), Equals, 3*Second)
+	c.Assert(Until(deadline), Equals, 2*Second)
+}
+
+func (s *FrozenSuite) TestNowUnix(c *C) {
+	c.Assert(NowUnix(), Equals, s.epoch.Unix())
+	c.Assert(NowUnixMilli(), Equals, s.epoch.UnixNano()/int64(Millisecond))
+	c.Assert(NowUnixNano(), Equals, s.epoch.UnixNano())
+
+	Advance(1500 * Millisecond)
+	c.Assert(NowUnix(), Equals, s.epoch.Unix()+1)
+	c.Assert(NowUnixMilli(), Equals, s.epoch.UnixNano()/int64(Millisecond)+1500)
+	c.Assert(NowUnixNano(), Equals, s.epoch.UnixNano()+int64(1500*Millisecond))
+
+	// Pairs with NewRFC822TimeFromUnix
+	c.Assert(NewRFC822TimeFromUnix(NowUnix()).Time, Equals, s.epoch.Add(Second))
+}
+
+// Frozen clocks advance independently of each other and of the global clock.
+func (s *FrozenSuite) TestNewFrozenClock(c *C) {
+	clock1 := NewFrozenClock(s.epoch)
+	clock2 := NewFrozenClock(s.epoch)
+	var clk Clock = clock1
+	timer1 := clk.NewTimer(100)
+	timer2 := clock2.NewTimer(100)
+
+	c.Assert(clock1.Advance(100), Equals, time.Duration(100))
+	c.Assert(s.epoch.Add(100), Equals, <-timer1.C())
+	assertNotFired(c, timer2.C())
+	c.Assert(clock2.Now(), Equals, s.epoch)
+	c.Assert(Now(), Equals, s.epoch)
+
+	c.Assert(clock2.Advance(150), Equals, time.Duration(150))
+	c.Assert(s.epoch.Add(100), Equals, <-timer2.C())
+	c.Assert(clock1.Now(), Equals, s.epoch.Add(100))
+	c.Assert(clock2.Now(), Equals, s.epoch.Add(150))
+}
+
+func (s *FrozenSuite) TestNewRealClock(c *C) {
+	clk := NewRealClock()
+	c.Assert(clk.Now().After(s.epoch), Equals, true)
+	c.Assert(Now(), Equals, s.epoch)
+}
+
 func assertHits(c *C, got <-chan int, want []int) {
 	for i, w := range want {
 		var g int
@@ -329,3 +490,43 @@ func assertNotFired(c *C, ch <-chan time.Time) {
 	default:
 	}
 }
+
+func TestFreezeForTest(t *testing.T) {
+	at := time.Date(2009, 2, 19, 0, 0, 0, 0, time.UTC)
+
+	t.Run("frozen", func(t *testing.T) {
+		clk := FreezeForTest(t, at)
+		if !Now().Equal(at) {
+			t.Fatalf("expected frozen time %s, got %s", at, Now())
+		}
+
+		clk.Advance(time.Second)
+		if Advance(time.Second) != 2*time.Second {
+			t.Fatalf("expected package and returned clock to share time")
+		}
+	})
+
+	if provider != realtime {
+		t.Fatalf("expected the clock to be unfrozen after the subtest")
+	}
+}
+
+// cleanups records the functions passed to Cleanup
+type cleanups []func()
+
+func (c *cleanups) Cleanup(f func()) {
+	*c = append(*c, f)
+}
+
+func TestFreezeForTestCleanup(t *testing.T) {
+	var c cleanups
+	FreezeForTest(&c, time.Date(2009, 2, 19, 0, 0, 0, 0, time.UTC))
+	if provider == realtime || len(c) != 1 {
+		t.Fatalf("expected the clock to be frozen with one cleanup, got %d", len(c))
+	}
+
+	c[0]()
+	if provider != realtime {
+		t.Fatalf("expected the clock to be unfrozen by the cleanup")
+	}
+}
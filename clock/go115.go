@@ -0,0 +1,10 @@
+//go:build go1.15
+// +build go1.15
+
+package clock
+
+import "time"
+
+func (t *systemTicker) Reset(d time.Duration) {
+	t.t.Reset(d)
+}
@@ -85,10 +85,6 @@ func Parse(layout, value string) (Time, error) {
 	return time.Parse(layout, value)
 }
 
-func ParseDuration(s string) (Duration, error) {
-	return time.ParseDuration(s)
-}
-
 func ParseInLocation(layout, value string, loc *Location) (Time, error) {
 	return time.ParseInLocation(layout, value, loc)
 }
@@ -97,10 +93,13 @@ func Unix(sec int64, nsec int64) Time {
 	return time.Unix(sec, nsec)
 }
 
+// Since returns the time elapsed since t according to the current clock, such
+// that under a frozen clock it only reflects calls to Advance.
 func Since(t Time) Duration {
 	return provider.Now().Sub(t)
 }
 
+// Until returns the duration until t according to the current clock.
 func Until(t Time) Duration {
 	return t.Sub(provider.Now())
 }
@@ -0,0 +1,49 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	locationMu sync.RWMutex
+	// The location set by SetLocation, nil if the SDK's local location is used
+	location *time.Location
+)
+
+// SetLocation overrides the location the package uses in place of the local
+// location of the machine. NowRFC822 returns the time in it and ParseRFC822
+// resolves zone abbreviations against it, such that tests which depend on the
+// local time zone behave the same on every machine. Passing nil restores the
+// local location.
+//
+//	moscow, _ := clock.LoadLocation("Europe/Moscow")
+//	clock.SetLocation(moscow)
+//	defer clock.SetLocation(nil)
+func SetLocation(loc *time.Location) {
+	locationMu.Lock()
+	location = loc
+	locationMu.Unlock()
+}
+
+// DefaultLocation returns the location set by SetLocation, or the local location
+// of the machine if none was set.
+func DefaultLocation() *time.Location {
+	locationMu.RLock()
+	defer locationMu.RUnlock()
+	if location == nil {
+		return time.Local
+	}
+	return location
+}
+
+// inDefaultLocation returns the time in the location set by SetLocation, or the
+// time unchanged if none was set
+func inDefaultLocation(t time.Time) time.Time {
+	locationMu.RLock()
+	defer locationMu.RUnlock()
+	if location == nil {
+		return t
+	}
+	return t.In(location)
+}
@@ -0,0 +1,233 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ManualClock is a Clock for benchmarks and tests that need full control over
+// time. Unlike FrozenClock, moving its time with Set or Add never fires timers,
+// sleepers or tickers; they only fire when Fire is called, the manual
+// equivalent of a tick (Tick is taken by the Clock interface). This makes it
+// possible to measure the logic driven by a timer separately from moving time.
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+	waiter *waiter
+}
+
+// NewManualClock returns a ManualClock set to the Unix epoch.
+func NewManualClock() *ManualClock {
+	return &ManualClock{now: time.Unix(0, 0).UTC()}
+}
+
+// Set sets the current time of the clock without firing any timers.
+func (mc *ManualClock) Set(t time.Time) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.now = t
+}
+
+// Add moves the current time of the clock by `d` without firing any timers,
+// and returns the new current time.
+func (mc *ManualClock) Add(d time.Duration) time.Time {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.now = mc.now.Add(d)
+	return mc.now
+}
+
+// Fire fires every timer that is due at the current time in deadline order,
+// and returns the number of timers fired. A ticker fires at most once per call
+// and is then scheduled for its next interval.
+func (mc *ManualClock) Fire() int {
+	mc.mu.Lock()
+	var due, pending []*manualTimer
+	for _, t := range mc.timers {
+		if t.when.After(mc.now) {
+			pending = append(pending, t)
+			continue
+		}
+		due = append(due, t)
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].when.Before(due[j].when)
+	})
+
+	var funcs []func()
+	for _, t := range due {
+		if t.c != nil {
+			select {
+			case t.c <- t.when:
+			default:
+			}
+		}
+		if t.f != nil {
+			funcs = append(funcs, t.f)
+		}
+		if t.interval != 0 {
+			t.when = t.when.Add(t.interval)
+			pending = append(pending, t)
+			continue
+		}
+		t.active = false
+	}
+	mc.timers = pending
+	mc.mu.Unlock()
+
+	// Called without the lock held, so they may schedule new timers
+	for _, f := range funcs {
+		f()
+	}
+	return len(due)
+}
+
+func (mc *ManualClock) Now() time.Time {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.now
+}
+
+func (mc *ManualClock) Sleep(d time.Duration) {
+	<-mc.NewTimer(d).C()
+}
+
+func (mc *ManualClock) After(d time.Duration) <-chan time.Time {
+	return mc.NewTimer(d).C()
+}
+
+func (mc *ManualClock) NewTimer(d time.Duration) Timer {
+	return mc.AfterFunc(d, nil)
+}
+
+func (mc *ManualClock) AfterFunc(d time.Duration, f func()) Timer {
+	t := &manualTimer{mc: mc, f: f}
+	if f == nil {
+		t.c = make(chan time.Time, 1)
+	}
+	mc.schedule(t, d, 0)
+	return t
+}
+
+func (mc *ManualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic(errors.New("non-positive interval for NewTicker"))
+	}
+	t := &manualTimer{mc: mc, c: make(chan time.Time, 1)}
+	mc.schedule(t, d, d)
+	return &manualTicker{t}
+}
+
+func (mc *ManualClock) Tick(d time.Duration) <-chan time.Time {
+	if d <= 0 {
+		return nil
+	}
+	return mc.NewTicker(d).C()
+}
+
+func (mc *ManualClock) Wait4Scheduled(count int, timeout time.Duration) bool {
+	mc.mu.Lock()
+	if len(mc.timers) >= count {
+		mc.mu.Unlock()
+		return true
+	}
+	if mc.waiter != nil {
+		panic("Concurrent call")
+	}
+	w := &waiter{count, make(chan struct{})}
+	mc.waiter = w
+	mc.mu.Unlock()
+
+	success := false
+	select {
+	case <-w.signalCh:
+		success = true
+	case <-time.After(timeout):
+	}
+	mc.mu.Lock()
+	mc.waiter = nil
+	mc.mu.Unlock()
+	return success
+}
+
+// schedule fires the timer after `d`, and then every `interval` if not zero
+func (mc *ManualClock) schedule(t *manualTimer, d, interval time.Duration) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	t.when = mc.now.Add(d)
+	t.interval = interval
+	if !t.active {
+		t.active = true
+		mc.timers = append(mc.timers, t)
+	}
+
+	if mc.waiter != nil && len(mc.timers) >= mc.waiter.count {
+		close(mc.waiter.signalCh)
+		mc.waiter = nil
+	}
+}
+
+func (mc *ManualClock) unschedule(t *manualTimer) bool {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if !t.active {
+		return false
+	}
+	for i, curr := range mc.timers {
+		if curr == t {
+			mc.timers = append(mc.timers[:i], mc.timers[i+1:]...)
+			break
+		}
+	}
+	t.active = false
+	return true
+}
+
+type manualTimer struct {
+	mc       *ManualClock
+	when     time.Time
+	interval time.Duration
+	active   bool
+	c        chan time.Time
+	f        func()
+}
+
+func (t *manualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTimer) Stop() bool {
+	return t.mc.unschedule(t)
+}
+
+func (t *manualTimer) Reset(d time.Duration) bool {
+	active := t.mc.unschedule(t)
+	t.mc.schedule(t, d, 0)
+	return active
+}
+
+type manualTicker struct {
+	t *manualTimer
+}
+
+func (t *manualTicker) C() <-chan time.Time {
+	return t.t.C()
+}
+
+func (t *manualTicker) Stop() {
+	t.t.Stop()
+}
+
+func (t *manualTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic(errors.New("non-positive interval for Ticker.Reset"))
+	}
+	t.t.mc.unschedule(t.t)
+	t.t.mc.schedule(t.t, d, d)
+}
@@ -0,0 +1,117 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var _ Clock = NewManualClock()
+
+func TestManualClockSetAdd(t *testing.T) {
+	mc := NewManualClock()
+	assert.Equal(t, time.Unix(0, 0).UTC(), mc.Now())
+
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mc.Set(start)
+	assert.Equal(t, start, mc.Now())
+
+	assert.Equal(t, start.Add(time.Minute), mc.Add(time.Minute))
+	assert.Equal(t, start.Add(time.Minute), mc.Now())
+}
+
+// Moving time does not fire a ticker, only Fire does.
+func TestManualClockTicker(t *testing.T) {
+	mc := NewManualClock()
+	ticker := mc.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// Not due yet
+	assert.Equal(t, 0, mc.Fire())
+	assertNoTick(t, ticker)
+
+	// Due, but not fired until Fire is called
+	mc.Add(time.Second)
+	assertNoTick(t, ticker)
+
+	assert.Equal(t, 1, mc.Fire())
+	assert.Equal(t, time.Unix(1, 0).UTC(), <-ticker.C())
+
+	// A ticker fires once per Fire even when several intervals are due
+	mc.Add(3 * time.Second)
+	for i := 2; i <= 4; i++ {
+		assert.Equal(t, 1, mc.Fire())
+		assert.Equal(t, time.Unix(int64(i), 0).UTC(), <-ticker.C())
+	}
+	assert.Equal(t, 0, mc.Fire())
+
+	// Reset schedules the next tick from the current time
+	ticker.Reset(time.Minute)
+	mc.Add(time.Second)
+	assert.Equal(t, 0, mc.Fire())
+	mc.Add(time.Minute)
+	assert.Equal(t, 1, mc.Fire())
+	assert.Equal(t, time.Unix(64, 0).UTC(), <-ticker.C())
+
+	ticker.Stop()
+	mc.Add(time.Hour)
+	assert.Equal(t, 0, mc.Fire())
+	assertNoTick(t, ticker)
+}
+
+func TestManualClockTimers(t *testing.T) {
+	mc := NewManualClock()
+
+	var fired []int
+	mc.AfterFunc(3*time.Second, func() { fired = append(fired, 3) })
+	mc.AfterFunc(1*time.Second, func() { fired = append(fired, 1) })
+	stopped := mc.AfterFunc(2*time.Second, func() { fired = append(fired, 2) })
+	timer := mc.NewTimer(5 * time.Second)
+
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+
+	mc.Add(4 * time.Second)
+	assert.Equal(t, 2, mc.Fire())
+	assert.Equal(t, []int{1, 3}, fired)
+
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired before it was due")
+	default:
+	}
+
+	mc.Add(time.Second)
+	assert.Equal(t, 1, mc.Fire())
+	assert.Equal(t, time.Unix(5, 0).UTC(), <-timer.C())
+	assert.False(t, timer.Stop())
+}
+
+func TestManualClockSleep(t *testing.T) {
+	mc := NewManualClock()
+	done := make(chan struct{})
+	go func() {
+		mc.Sleep(time.Second)
+		close(done)
+	}()
+	assert.True(t, mc.Wait4Scheduled(1, time.Second))
+
+	mc.Add(time.Second)
+	select {
+	case <-done:
+		t.Fatal("sleep returned before Fire")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	mc.Fire()
+	<-done
+}
+
+func assertNoTick(t *testing.T, ticker Ticker) {
+	select {
+	case tick := <-ticker.C():
+		t.Fatalf("unexpected tick at %s", tick)
+	default:
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !go1.15
+// +build !go1.15
+
+package clock
+
+import (
+	"errors"
+	"time"
+)
+
+// Reset emulates time.Ticker.Reset that is only available since go1.15 by
+// replacing the underlying ticker, so C() must be called again after Reset.
+func (t *systemTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic(errors.New("non-positive interval for Ticker.Reset"))
+	}
+	t.t.Stop()
+	t.t = time.NewTicker(d)
+}
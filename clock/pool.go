@@ -0,0 +1,50 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+var timerPool sync.Pool
+
+// AcquireTimer is like NewTimer but reuses a timer previously returned with
+// ReleaseTimer if one is available, which avoids allocating a timer on every
+// iteration of a hot retry loop. While time is frozen it returns a new frozen
+// timer instead.
+//
+//	timer := clock.AcquireTimer(backOff.Next())
+//	defer clock.ReleaseTimer(timer)
+//
+//	select {
+//	case <-timer.C():
+//	case <-ctx.Done():
+//	}
+func AcquireTimer(d time.Duration) Timer {
+	if provider != realtime {
+		return provider.NewTimer(d)
+	}
+	if t, ok := timerPool.Get().(*systemTimer); ok {
+		t.Reset(d)
+		return t
+	}
+	return realtime.NewTimer(d)
+}
+
+// ReleaseTimer stops a timer returned by AcquireTimer and returns it to the pool.
+// If the timer fired without its value being received the value is drained, so
+// the next AcquireTimer caller does not receive a stale fire. The timer must not
+// be used after it is released.
+func ReleaseTimer(t Timer) {
+	st, ok := t.(*systemTimer)
+	if !ok {
+		t.Stop()
+		return
+	}
+	if !st.Stop() {
+		select {
+		case <-st.C():
+		default:
+		}
+	}
+	timerPool.Put(st)
+}
@@ -0,0 +1,79 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type PoolSuite struct{}
+
+var _ = Suite(&PoolSuite{})
+
+func (s *PoolSuite) TestReusedTimerFires(c *C) {
+	for i := 0; i < 3; i++ {
+		timer := AcquireTimer(20 * time.Millisecond)
+		start := time.Now()
+		<-timer.C()
+		c.Assert(time.Since(start) >= 20*time.Millisecond, Equals, true)
+		ReleaseTimer(timer)
+	}
+}
+
+// A timer released after firing without its value being received must not fire
+// immediately once reused.
+func (s *PoolSuite) TestReleaseDrainsStaleFire(c *C) {
+	timer := AcquireTimer(time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	ReleaseTimer(timer)
+
+	timer = AcquireTimer(100 * time.Millisecond)
+	defer ReleaseTimer(timer)
+	select {
+	case <-timer.C():
+		c.Fatal("reused timer delivered a stale fire")
+	case <-time.After(50 * time.Millisecond):
+	}
+	<-timer.C()
+}
+
+func (s *PoolSuite) TestReleaseUnfired(c *C) {
+	timer := AcquireTimer(time.Hour)
+	ReleaseTimer(timer)
+
+	timer = AcquireTimer(10 * time.Millisecond)
+	defer ReleaseTimer(timer)
+	select {
+	case <-timer.C():
+	case <-time.After(time.Second):
+		c.Fatal("reused timer did not fire")
+	}
+}
+
+func (s *PoolSuite) TestAcquireTimerFrozen(c *C) {
+	defer Freeze(Now()).Unfreeze()
+
+	timer := AcquireTimer(100 * time.Millisecond)
+	defer ReleaseTimer(timer)
+	Advance(100 * time.Millisecond)
+	select {
+	case <-timer.C():
+	default:
+		c.Fatal("frozen timer did not fire")
+	}
+}
+
+func BenchmarkAcquireTimer(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ReleaseTimer(AcquireTimer(time.Hour))
+	}
+}
+
+func BenchmarkNewTimer(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		time.NewTimer(time.Hour).Stop()
+	}
+}
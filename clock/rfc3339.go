@@ -0,0 +1,52 @@
+package clock
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Allows seamless JSON encoding/decoding of RFC3339 formatted timestamps with
+// sub-second precision and the offset preserved.
+type RFC3339Time struct {
+	Time
+}
+
+// NewRFC3339Time creates RFC3339Time from a standard Time.
+func NewRFC3339Time(t Time) RFC3339Time {
+	return RFC3339Time{Time: t}
+}
+
+// NewRFC3339TimeFromUnix creates RFC3339Time from a Unix timestamp (seconds from Epoch).
+func NewRFC3339TimeFromUnix(timestamp int64) RFC3339Time {
+	return RFC3339Time{Time: Unix(timestamp, 0).UTC()}
+}
+
+// MarshalJSON marshals the time as a quoted RFC3339Nano string, a zero time is
+// marshaled as JSON `null`.
+func (t RFC3339Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(strconv.Quote(t.Format(RFC3339Nano))), nil
+}
+
+// UnmarshalJSON parses a quoted RFC3339 string. Like time.Time, JSON `null`
+// is a no-op, so the time of a new value is left zero.
+func (t *RFC3339Time) UnmarshalJSON(s []byte) error {
+	if string(s) == "null" {
+		return nil
+	}
+	q, err := strconv.Unquote(string(s))
+	if err != nil {
+		return errors.Errorf("RFC3339Time: expected a quoted string, got %s", truncate(s, 32))
+	}
+	if t.Time, err = Parse(RFC3339Nano, q); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t RFC3339Time) String() string {
+	return t.Format(RFC3339Nano)
+}
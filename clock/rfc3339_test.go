@@ -0,0 +1,86 @@
+package clock
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRFC3339Struct struct {
+	Time RFC3339Time `json:"ts"`
+}
+
+func TestRFC3339New(t *testing.T) {
+	stdTime, err := Parse(RFC3339Nano, "2019-08-29T11:20:07.123456789+03:30")
+	assert.NoError(t, err)
+
+	rfc3339TimeFromTime := NewRFC3339Time(stdTime)
+	assert.True(t, rfc3339TimeFromTime.Equal(stdTime))
+	assert.Equal(t, "2019-08-29T11:20:07.123456789+03:30", rfc3339TimeFromTime.String())
+
+	rfc3339TimeFromUnix := NewRFC3339TimeFromUnix(1567077607)
+	assert.Equal(t, "2019-08-29T11:20:07Z", rfc3339TimeFromUnix.String())
+}
+
+func TestRFC3339Marshaling(t *testing.T) {
+	for i, tc := range []struct {
+		in      string
+		encoded string
+	}{{
+		in:      "2019-08-29T11:20:07.123456789+03:30",
+		encoded: `{"ts":"2019-08-29T11:20:07.123456789+03:30"}`,
+	}, {
+		in:      "2019-08-29T11:20:07.1-07:00",
+		encoded: `{"ts":"2019-08-29T11:20:07.1-07:00"}`,
+	}, {
+		in:      "2019-08-29T11:20:07Z",
+		encoded: `{"ts":"2019-08-29T11:20:07Z"}`,
+	}} {
+		tcDesc := fmt.Sprintf("Test case #%d: %v", i, tc)
+
+		stdTime, err := Parse(RFC3339Nano, tc.in)
+		assert.NoError(t, err, tcDesc)
+
+		encoded, err := json.Marshal(&testRFC3339Struct{Time: NewRFC3339Time(stdTime)})
+		assert.NoError(t, err, tcDesc)
+		assert.Equal(t, tc.encoded, string(encoded), tcDesc)
+
+		var out testRFC3339Struct
+		err = json.Unmarshal(encoded, &out)
+		assert.NoError(t, err, tcDesc)
+		assert.True(t, stdTime.Equal(out.Time.Time), tcDesc)
+		_, offset := out.Time.Zone()
+		_, wantOffset := stdTime.Zone()
+		assert.Equal(t, wantOffset, offset, tcDesc)
+	}
+}
+
+func TestRFC3339ZeroValue(t *testing.T) {
+	encoded, err := json.Marshal(&testRFC3339Struct{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ts":null}`, string(encoded))
+
+	var out testRFC3339Struct
+	err = json.Unmarshal(encoded, &out)
+	assert.NoError(t, err)
+	assert.True(t, out.Time.IsZero())
+}
+
+func TestRFC3339UnmarshalingError(t *testing.T) {
+	for _, tc := range []struct {
+		inEncoded string
+		outError  string
+	}{{
+		inEncoded: `{"ts": "Thu, 29 Aug 2019 11:20:07 GMT"}`,
+		outError:  `parsing time "Thu, 29 Aug 2019 11:20:07 GMT" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "Thu, 29 Aug 2019 11:20:07 GMT" as "2006"`,
+	}, {
+		inEncoded: `{"ts": 42}`,
+		outError:  "RFC3339Time: expected a quoted string, got 42",
+	}} {
+		var ts testRFC3339Struct
+		err := json.Unmarshal([]byte(tc.inEncoded), &ts)
+		assert.EqualError(t, err, tc.outError)
+	}
+}
@@ -1,9 +1,50 @@
 package clock
 
 import (
+	"database/sql/driver"
 	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	abbrevMu sync.RWMutex
+	abbrevs  = make(map[string]int)
 )
 
+// RegisterTimezoneAbbrev registers the offset in seconds east of UTC that
+// RFC822Time should use when parsing the zone abbreviation `name`. Unless
+// registered, an abbreviation that is not known to the local time zone is
+// parsed with a zero offset.
+//
+// Abbreviations are inherently ambiguous, `IST` for example is used for India,
+// Ireland and Israel, so register only those that mean the same thing to every
+// producer of timestamps your service consumes.
+//
+//	clock.RegisterTimezoneAbbrev("CEST", 2*60*60)
+func RegisterTimezoneAbbrev(name string, offset int) {
+	abbrevMu.Lock()
+	abbrevs[name] = offset
+	abbrevMu.Unlock()
+}
+
+// unregisterTimezoneAbbrev removes an abbreviation registered by RegisterTimezoneAbbrev
+func unregisterTimezoneAbbrev(name string) {
+	abbrevMu.Lock()
+	delete(abbrevs, name)
+	abbrevMu.Unlock()
+}
+
+// lookupTimezoneAbbrev returns the registered offset for the abbreviation
+func lookupTimezoneAbbrev(name string) (int, bool) {
+	abbrevMu.RLock()
+	defer abbrevMu.RUnlock()
+	offset, ok := abbrevs[name]
+	return offset, ok
+}
+
 // Allows seamless JSON encoding/decoding of rfc822 formatted timestamps.
 // https://www.ietf.org/rfc/rfc822.txt section 5.
 type RFC822Time struct {
@@ -16,32 +57,225 @@ func NewRFC822Time(t Time) RFC822Time {
 	return RFC822Time{Time: t.Truncate(Second)}
 }
 
+// NowRFC822 returns the current time of the clock as RFC822Time truncated down to
+// second precision, it respects Freeze like Now() and SetLocation.
+func NowRFC822() RFC822Time {
+	return NewRFC822Time(inDefaultLocation(Now()))
+}
+
 // NewRFC822Time creates RFC822Time from a Unix timestamp (seconds from Epoch).
 func NewRFC822TimeFromUnix(timestamp int64) RFC822Time {
 	return RFC822Time{Time: Unix(timestamp, 0).UTC()}
 }
 
+// NewRFC822TimeInLocation creates RFC822Time from a standard Time converted to
+// `loc`, which determines the zone that is marshaled. A named location such as
+// Europe/Moscow produces an abbreviation like `MSK`, while a FixedZone without a
+// name produces a numeric offset like `+0300`. The created value is truncated
+// down to second precision.
+func NewRFC822TimeInLocation(t Time, loc *Location) RFC822Time {
+	return RFC822Time{Time: t.In(loc).Truncate(Second)}
+}
+
+// OrNil returns nil for the zero time and a pointer to a copy otherwise. A
+// struct is never considered empty by `omitempty`, so use a pointer field to
+// leave out zero times when marshaling:
+//
+//	type Event struct {
+//		Expires *clock.RFC822Time `json:"expires,omitempty"`
+//	}
+//
+//	e := Event{Expires: expires.OrNil()}
+func (t RFC822Time) OrNil() *RFC822Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Layouts of the obsolete RFC822 syntax with a 2 digit year
+const (
+	rfc822TwoDigitYear  = "Mon, 02 Jan 06 15:04:05 MST"
+	rfc822TwoDigitYearZ = "Mon, 02 Jan 06 15:04:05 -0700"
+)
+
+// Layouts of the RFC822 syntax without the optional day of the week
+const (
+	rfc822NoWeekday  = "2 Jan 2006 15:04:05 MST"
+	rfc822NoWeekdayZ = "2 Jan 2006 15:04:05 -0700"
+)
+
+// ParseRFC822 parses an RFC822 timestamp such as an HTTP or email `Date`
+// header. It parses RFC1123 and falls back to RFC1123Z if the zone is a numeric
+// offset. Zone abbreviations registered with RegisterTimezoneAbbrev take
+// precedence over those of the location set by SetLocation, or the local location
+// if none was set. The `-0000` offset is preserved, so it survives a round trip.
+//
+// If both fail, the obsolete 2 digit years of legacy producers are accepted and
+// interpreted per RFC 2822 section 4.3, 50 through 99 as 19xx and 00 through 49
+// as 20xx. The day of the week is optional in RFC822, so dates without it such
+// as `29 Aug 2019 11:20:07 GMT` are accepted last. Errors always refer to the
+// 4 digit year layouts with the day of the week.
+func ParseRFC822(s string) (Time, error) {
+	t, err := parseRFC822(s, RFC1123, RFC1123Z)
+	if err == nil {
+		return t, nil
+	}
+	if t, err := parseRFC822(s, rfc822TwoDigitYear, rfc822TwoDigitYearZ); err == nil {
+		// Parse interprets 50 through 68 as 20xx
+		if t.Year() >= 2050 {
+			t = t.AddDate(-100, 0, 0)
+		}
+		return t, nil
+	}
+	if t, err := parseRFC822(s, rfc822NoWeekday, rfc822NoWeekdayZ); err == nil {
+		return t, nil
+	}
+	return Time{}, err
+}
+
+// parseRFC822 parses `layout` and falls back to `layoutZ` if the zone is a numeric offset
+func parseRFC822(s, layout, layoutZ string) (Time, error) {
+	t, err := ParseInLocation(layout, s, DefaultLocation())
+	if err == nil {
+		name, _ := t.Zone()
+		if offset, ok := lookupTimezoneAbbrev(name); ok {
+			t = Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(),
+				0, FixedZone(name, offset))
+		}
+		return t, nil
+	}
+	if err, ok := err.(*ParseError); !ok || err.LayoutElem != "MST" {
+		return Time{}, err
+	}
+	if t, err = ParseInLocation(layoutZ, s, DefaultLocation()); err != nil {
+		return Time{}, err
+	}
+	// RFC 2822 uses `-0000` for a UTC time whose local offset is unknown. Name
+	// the zone so the time is marshaled back as `-0000` and not `+0000`.
+	if strings.HasSuffix(s, " -0000") {
+		t = t.In(FixedZone("-0000", 0))
+	}
+	return t, nil
+}
+
+// MarshalJSON marshals the time as a quoted RFC1123 string, a zero time is
+// marshaled as JSON `null`.
 func (t RFC822Time) MarshalJSON() ([]byte, error) {
-	return []byte(strconv.Quote(t.Format(RFC1123))), nil
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	b, err := t.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strconv.Quote(string(b))), nil
 }
 
+// UnmarshalJSON parses a quoted RFC1123 or RFC1123Z string. Like time.Time,
+// JSON `null` is a no-op, so the time of a new value is left zero.
 func (t *RFC822Time) UnmarshalJSON(s []byte) error {
+	if string(s) == "null" {
+		return nil
+	}
 	q, err := strconv.Unquote(string(s))
 	if err != nil {
-		return err
+		return errors.Errorf("RFC822Time: expected a quoted string, got %s", truncate(s, 32))
 	}
-	if t.Time, err = Parse(RFC1123, q); err == nil {
-		return nil
+	return t.UnmarshalText([]byte(q))
+}
+
+// truncate shortens s to at most n bytes for inclusion in error messages.
+func truncate(s []byte, n int) string {
+	if len(s) <= n {
+		return string(s)
 	}
-	if err, ok := err.(*ParseError); !ok || err.LayoutElem != "MST" {
+	return string(s[:n]) + "..."
+}
+
+// MarshalText implements encoding.TextMarshaler, the time is formatted as RFC1123.
+func (t RFC822Time) MarshalText() ([]byte, error) {
+	return t.AppendText(nil)
+}
+
+// AppendText is like MarshalText but appends to b. It shadows the RFC3339
+// AppendText promoted from the embedded Time, which newer encoders prefer.
+func (t RFC822Time) AppendText(b []byte) ([]byte, error) {
+	return t.AppendFormat(b, RFC1123), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseRFC822.
+func (t *RFC822Time) UnmarshalText(s []byte) error {
+	var err error
+	if t.Time, err = ParseRFC822(string(s)); err != nil {
 		return err
 	}
-	if t.Time, err = Parse(RFC1123Z, q); err != nil {
+	return nil
+}
+
+// MarshalYAML marshals the time as an RFC1123 string, a zero time is
+// marshaled as YAML `null`.
+func (t RFC822Time) MarshalYAML() (interface{}, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	b, err := t.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// UnmarshalYAML parses an RFC1123 or RFC1123Z string.
+func (t *RFC822Time) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
 		return err
 	}
-	return nil
+	return t.UnmarshalText([]byte(s))
+}
+
+// Value implements driver.Valuer so the time is stored as a native timestamp,
+// truncated down to second precision. The zero time is stored as NULL, which
+// Scan reads back as the zero time.
+func (t RFC822Time) Value() (driver.Value, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return t.Truncate(Second), nil
+}
+
+// Scan implements sql.Scanner. It accepts a Time, or an RFC1123 or RFC1123Z
+// formatted []byte or string. A NULL column leaves the time zero.
+func (t *RFC822Time) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		t.Time = Time{}
+		return nil
+	case Time:
+		*t = NewRFC822Time(v)
+		return nil
+	case []byte:
+		return errors.Wrap(t.UnmarshalText(v), "while scanning []byte")
+	case string:
+		return errors.Wrap(t.UnmarshalText([]byte(v)), "while scanning string")
+	}
+	return errors.Errorf("bad type %T", src)
 }
 
 func (t RFC822Time) String() string {
-	return t.Format(RFC1123)
+	return FormatRFC822(t.Time)
+}
+
+// FormatRFC822 formats the time as RFC1123 truncated to second precision, the
+// zone is written as its abbreviation, or as a numeric offset if it has none.
+// The output is the same as RFC822Time.String().
+func FormatRFC822(t Time) string {
+	return t.Truncate(Second).Format(RFC1123)
+}
+
+// FormatRFC822Z formats the time as RFC1123Z truncated to second precision,
+// the zone is always written as a numeric offset.
+func FormatRFC822Z(t Time) string {
+	return t.Truncate(Second).Format(RFC1123Z)
 }
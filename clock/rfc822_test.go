@@ -1,19 +1,36 @@
 package clock
 
 import (
+	"encoding"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 )
 
 type testStruct struct {
-	Time RFC822Time `json:"ts"`
+	Time RFC822Time `json:"ts" yaml:"ts"`
+}
+
+// useMoscow sets the location of the package to Europe/Moscow, such that the
+// `MSK` abbreviation is known regardless of the local time zone of the machine,
+// and returns a function which restores the local location.
+func useMoscow(t *testing.T) func() {
+	moscow, err := LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetLocation(moscow)
+	return func() { SetLocation(nil) }
 }
 
 func TestRFC822New(t *testing.T) {
-	stdTime, err := Parse(RFC3339, "2019-08-29T11:20:07.123456+03:00")
+	defer useMoscow(t)()
+
+	stdTime, err := ParseInLocation(RFC3339, "2019-08-29T11:20:07.123456+03:00", DefaultLocation())
 	assert.NoError(t, err)
 
 	rfc822TimeFromTime := NewRFC822Time(stdTime)
@@ -25,6 +42,80 @@ func TestRFC822New(t *testing.T) {
 	assert.Equal(t, "Thu, 29 Aug 2019 08:20:07 UTC", rfc822TimeFromUnix.String())
 }
 
+func TestNowRFC822(t *testing.T) {
+	at := Date(2019, 8, 29, 11, 20, 7, 123456789, UTC)
+	defer Freeze(at).Unfreeze()
+
+	now := NowRFC822()
+	assert.Equal(t, Date(2019, 8, 29, 11, 20, 7, 0, UTC), now.Time)
+
+	Advance(1500 * Millisecond)
+	assert.Equal(t, Date(2019, 8, 29, 11, 20, 8, 0, UTC), NowRFC822().Time)
+}
+
+func TestRFC822NewInLocation(t *testing.T) {
+	stdTime, err := Parse(RFC3339, "2019-08-29T08:20:07.123456Z")
+	assert.NoError(t, err)
+
+	moscow, err := LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		loc  *Location
+		want string
+	}{{
+		loc:  moscow,
+		want: "Thu, 29 Aug 2019 11:20:07 MSK",
+	}, {
+		loc:  FixedZone("", 3*60*60),
+		want: "Thu, 29 Aug 2019 11:20:07 +0300",
+	}, {
+		loc:  UTC,
+		want: "Thu, 29 Aug 2019 08:20:07 UTC",
+	}} {
+		rfc822Time := NewRFC822TimeInLocation(stdTime, tc.loc)
+		assert.Equal(t, tc.want, rfc822Time.String())
+		assert.Equal(t, tc.loc, rfc822Time.Location())
+		assert.Equal(t, 0, rfc822Time.Nanosecond())
+		assert.True(t, stdTime.Truncate(Second).Equal(rfc822Time.Time))
+	}
+}
+
+func TestFormatRFC822(t *testing.T) {
+	stdTime, err := Parse(RFC3339Nano, "2019-08-29T08:20:07.999999Z")
+	assert.NoError(t, err)
+
+	moscow, err := LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		loc     *Location
+		rfc822  string
+		rfc822Z string
+	}{{
+		loc:     UTC,
+		rfc822:  "Thu, 29 Aug 2019 08:20:07 UTC",
+		rfc822Z: "Thu, 29 Aug 2019 08:20:07 +0000",
+	}, {
+		loc:     moscow,
+		rfc822:  "Thu, 29 Aug 2019 11:20:07 MSK",
+		rfc822Z: "Thu, 29 Aug 2019 11:20:07 +0300",
+	}, {
+		loc:     FixedZone("", 3*60*60+30*60),
+		rfc822:  "Thu, 29 Aug 2019 11:50:07 +0330",
+		rfc822Z: "Thu, 29 Aug 2019 11:50:07 +0330",
+	}, {
+		loc:     FixedZone("-0000", 0),
+		rfc822:  "Thu, 29 Aug 2019 08:20:07 -0000",
+		rfc822Z: "Thu, 29 Aug 2019 08:20:07 +0000",
+	}} {
+		inLoc := stdTime.In(tc.loc)
+		assert.Equal(t, tc.rfc822, FormatRFC822(inLoc))
+		assert.Equal(t, tc.rfc822Z, FormatRFC822Z(inLoc))
+		assert.Equal(t, NewRFC822Time(inLoc).String(), FormatRFC822(inLoc))
+	}
+}
+
 // NewRFC822Time truncates to second precision.
 func TestRFC822SecondPrecision(t *testing.T) {
 	stdTime1, err := Parse(RFC3339, "2019-08-29T11:20:07.111111+03:00")
@@ -51,6 +142,8 @@ func TestRFC822Marshaling(t *testing.T) {
 }
 
 func TestRFC822Unmarshaling(t *testing.T) {
+	defer useMoscow(t)()
+
 	for i, tc := range []struct {
 		inRFC822   string
 		outRFC3339 string
@@ -95,6 +188,31 @@ func TestRFC822Unmarshaling(t *testing.T) {
 	}
 }
 
+// `-0000` means UTC with an unknown local offset and must not become `+0000`.
+func TestRFC822MinusZeroRoundTrip(t *testing.T) {
+	for _, in := range []string{
+		"Thu, 29 Aug 2019 11:20:07 -0000",
+		"Thu, 29 Aug 2019 11:20:07 +0000",
+	} {
+		var ts testStruct
+		err := json.Unmarshal([]byte(fmt.Sprintf(`{"ts":"%s"}`, in)), &ts)
+		assert.NoError(t, err)
+		_, offset := ts.Time.Zone()
+		assert.Equal(t, 0, offset)
+
+		encoded, err := json.Marshal(&ts)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf(`{"ts":"%s"}`, in), string(encoded))
+
+		// And again through the text marshaling
+		var rt RFC822Time
+		assert.NoError(t, rt.UnmarshalText(encoded[7:len(encoded)-2]))
+		text, err := rt.MarshalText()
+		assert.NoError(t, err)
+		assert.Equal(t, in, string(text))
+	}
+}
+
 func TestRFC822UnmarshalingError(t *testing.T) {
 	for _, tc := range []struct {
 		inEncoded string
@@ -107,10 +225,402 @@ func TestRFC822UnmarshalingError(t *testing.T) {
 		outError:  `parsing time "foo" as "Mon, 02 Jan 2006 15:04:05 MST": cannot parse "foo" as "Mon"`,
 	}, {
 		inEncoded: `{"ts": 42}`,
-		outError:  "invalid syntax",
+		outError:  "RFC822Time: expected a quoted string, got 42",
+	}, {
+		inEncoded: `{"ts": true}`,
+		outError:  "RFC822Time: expected a quoted string, got true",
+	}, {
+		inEncoded: `{"ts": 12345678901234567890123456789012345678901234567890}`,
+		outError:  "RFC822Time: expected a quoted string, got 12345678901234567890123456789012...",
 	}} {
 		var ts testStruct
 		err := json.Unmarshal([]byte(tc.inEncoded), &ts)
 		assert.EqualError(t, err, tc.outError)
 	}
 }
+
+func TestRFC822ZeroValue(t *testing.T) {
+	for i, tc := range []struct {
+		in      testStruct
+		encoded string
+	}{{
+		in:      testStruct{},
+		encoded: `{"ts":null}`,
+	}, {
+		in:      testStruct{Time: NewRFC822Time(Time{})},
+		encoded: `{"ts":null}`,
+	}, {
+		in:      testStruct{Time: NewRFC822TimeFromUnix(1567077607)},
+		encoded: `{"ts":"Thu, 29 Aug 2019 11:20:07 UTC"}`,
+	}} {
+		tcDesc := fmt.Sprintf("Test case #%d: %v", i, tc)
+
+		encoded, err := json.Marshal(&tc.in)
+		assert.NoError(t, err, tcDesc)
+		assert.Equal(t, tc.encoded, string(encoded), tcDesc)
+
+		var out testStruct
+		err = json.Unmarshal(encoded, &out)
+		assert.NoError(t, err, tcDesc)
+		assert.True(t, tc.in.Time.Equal(out.Time.Time), tcDesc)
+		assert.Equal(t, tc.in.Time.IsZero(), out.Time.IsZero(), tcDesc)
+	}
+}
+
+func TestRFC822TextMarshaling(t *testing.T) {
+	var marshaler encoding.TextMarshaler = NewRFC822TimeFromUnix(1567077607)
+	text, err := marshaler.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "Thu, 29 Aug 2019 11:20:07 UTC", string(text))
+
+	var rfc822Time RFC822Time
+	var unmarshaler encoding.TextUnmarshaler = &rfc822Time
+	err = unmarshaler.UnmarshalText([]byte("Thu, 29 Aug 2019 11:20:07 +0330"))
+	assert.NoError(t, err)
+	assert.Equal(t, "2019-08-29T11:20:07+03:30", rfc822Time.Format(RFC3339))
+}
+
+// Map keys are encoded using encoding.TextMarshaler.
+func TestRFC822TextMapKey(t *testing.T) {
+	in := map[RFC822Time]int{NewRFC822TimeFromUnix(1567077607): 1}
+	encoded, err := json.Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Thu, 29 Aug 2019 11:20:07 UTC":1}`, string(encoded))
+
+	var out map[RFC822Time]int
+	err = json.Unmarshal(encoded, &out)
+	assert.NoError(t, err)
+	for k, v := range out {
+		assert.Equal(t, int64(1567077607), k.Unix())
+		assert.Equal(t, 1, v)
+	}
+}
+
+func TestRFC822TextURLQuery(t *testing.T) {
+	for _, in := range []string{
+		"Thu, 29 Aug 2019 11:20:07 GMT",
+		"Thu, 29 Aug 2019 11:20:07 +0330",
+	} {
+		var rfc822Time RFC822Time
+		assert.NoError(t, rfc822Time.UnmarshalText([]byte(in)))
+
+		text, err := rfc822Time.MarshalText()
+		assert.NoError(t, err)
+		query := url.Values{"since": []string{string(text)}}
+
+		parsed, err := url.ParseQuery(query.Encode())
+		assert.NoError(t, err)
+
+		var out RFC822Time
+		assert.NoError(t, out.UnmarshalText([]byte(parsed.Get("since"))))
+		assert.True(t, rfc822Time.Equal(out.Time), "want=%s, got=%s", rfc822Time, out)
+		assert.Equal(t, in, out.String())
+	}
+}
+
+func TestRFC822YAMLMarshaling(t *testing.T) {
+	stdTime, err := Parse(RFC3339Nano, "2019-08-29T11:20:07.123456789+03:30")
+	assert.NoError(t, err)
+
+	for i, tc := range []struct {
+		in      testStruct
+		encoded string
+	}{{
+		in:      testStruct{},
+		encoded: "ts: null\n",
+	}, {
+		in:      testStruct{Time: NewRFC822Time(stdTime)},
+		encoded: "ts: Thu, 29 Aug 2019 11:20:07 +0330\n",
+	}, {
+		in:      testStruct{Time: NewRFC822TimeFromUnix(1567077607)},
+		encoded: "ts: Thu, 29 Aug 2019 11:20:07 UTC\n",
+	}} {
+		tcDesc := fmt.Sprintf("Test case #%d: %v", i, tc)
+
+		encoded, err := yaml.Marshal(&tc.in)
+		assert.NoError(t, err, tcDesc)
+		assert.Equal(t, tc.encoded, string(encoded), tcDesc)
+
+		var out testStruct
+		err = yaml.Unmarshal(encoded, &out)
+		assert.NoError(t, err, tcDesc)
+		assert.True(t, tc.in.Time.Equal(out.Time.Time),
+			"%s: want=%s, got=%s", tcDesc, tc.in.Time, out.Time)
+	}
+}
+
+func TestRFC822YAMLUnmarshaling(t *testing.T) {
+	defer useMoscow(t)()
+
+	for i, tc := range []struct {
+		inRFC822   string
+		outRFC3339 string
+	}{{
+		inRFC822:   "Thu, 29 Aug 2019 11:20:07 GMT",
+		outRFC3339: "2019-08-29T11:20:07Z",
+	}, {
+		inRFC822:   "Thu, 29 Aug 2019 11:20:07 MSK",
+		outRFC3339: "2019-08-29T11:20:07+03:00",
+	}, {
+		inRFC822:   "Thu, 29 Aug 2019 11:20:07 +0330",
+		outRFC3339: "2019-08-29T11:20:07+03:30",
+	}} {
+		tcDesc := fmt.Sprintf("Test case #%d: %v", i, tc)
+		var ts testStruct
+
+		err := yaml.Unmarshal([]byte(fmt.Sprintf("ts: %s\n", tc.inRFC822)), &ts)
+		assert.NoError(t, err, tcDesc)
+		assert.Equal(t, tc.outRFC3339, ts.Time.Format(RFC3339), tcDesc)
+	}
+
+	var ts testStruct
+	err := yaml.Unmarshal([]byte("ts: foo\n"), &ts)
+	assert.EqualError(t, err, `parsing time "foo" as "Mon, 02 Jan 2006 15:04:05 MST": cannot parse "foo" as "Mon"`)
+}
+
+func TestRFC822Value(t *testing.T) {
+	stdTime, err := Parse(RFC3339Nano, "2019-08-29T11:20:07.123456789+03:30")
+	assert.NoError(t, err)
+
+	v, err := RFC822Time{Time: stdTime}.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, stdTime.Truncate(Second), v)
+}
+
+func TestRFC822ValueZero(t *testing.T) {
+	v, err := RFC822Time{}.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	var rt RFC822Time
+	assert.NoError(t, rt.Scan(v))
+	assert.True(t, rt.IsZero())
+}
+
+func TestRFC822Scan(t *testing.T) {
+	defer useMoscow(t)()
+
+	stdTime, err := Parse(RFC3339Nano, "2019-08-29T11:20:07.123456789+03:30")
+	assert.NoError(t, err)
+
+	for i, tc := range []struct {
+		src        interface{}
+		outRFC3339 string
+	}{{
+		src:        stdTime,
+		outRFC3339: "2019-08-29T11:20:07+03:30",
+	}, {
+		src:        []byte("Thu, 29 Aug 2019 11:20:07 MSK"),
+		outRFC3339: "2019-08-29T11:20:07+03:00",
+	}, {
+		src:        "Thu, 29 Aug 2019 11:20:07 +0330",
+		outRFC3339: "2019-08-29T11:20:07+03:30",
+	}} {
+		tcDesc := fmt.Sprintf("Test case #%d: %v", i, tc)
+		var rfc822Time RFC822Time
+
+		err := rfc822Time.Scan(tc.src)
+		assert.NoError(t, err, tcDesc)
+		assert.Equal(t, tc.outRFC3339, rfc822Time.Format(RFC3339Nano), tcDesc)
+	}
+
+	rfc822Time := NewRFC822TimeFromUnix(1567077607)
+	assert.NoError(t, rfc822Time.Scan(nil))
+	assert.True(t, rfc822Time.IsZero())
+}
+
+func TestRFC822ScanError(t *testing.T) {
+	var rfc822Time RFC822Time
+	err := rfc822Time.Scan(int64(1567077607))
+	assert.EqualError(t, err, "bad type int64")
+
+	err = rfc822Time.Scan("foo")
+	assert.EqualError(t, err, `while scanning string: parsing time "foo" as "Mon, 02 Jan 2006 15:04:05 MST": cannot parse "foo" as "Mon"`)
+}
+
+func TestRFC822RegisterTimezoneAbbrev(t *testing.T) {
+	var ts testStruct
+	inEncoded := []byte(`{"ts":"Thu, 29 Aug 2019 11:20:07 CEST"}`)
+
+	// Unknown abbreviations resolve to a zero offset
+	err := json.Unmarshal(inEncoded, &ts)
+	assert.NoError(t, err)
+	assert.Equal(t, "2019-08-29T11:20:07Z", ts.Time.Format(RFC3339))
+
+	RegisterTimezoneAbbrev("CEST", 2*60*60)
+	defer unregisterTimezoneAbbrev("CEST")
+
+	err = json.Unmarshal(inEncoded, &ts)
+	assert.NoError(t, err)
+	assert.Equal(t, "2019-08-29T11:20:07+02:00", ts.Time.Format(RFC3339))
+
+	actualEncoded, err := json.Marshal(&ts)
+	assert.NoError(t, err)
+	assert.Equal(t, string(inEncoded), string(actualEncoded))
+}
+
+func TestParseRFC822(t *testing.T) {
+	defer useMoscow(t)()
+
+	for i, tc := range []struct {
+		in         string
+		outRFC3339 string
+	}{{
+		in:         "Thu, 29 Aug 2019 11:20:07 GMT",
+		outRFC3339: "2019-08-29T11:20:07Z",
+	}, {
+		in:         "Thu, 29 Aug 2019 11:20:07 MSK",
+		outRFC3339: "2019-08-29T11:20:07+03:00",
+	}, {
+		in:         "Thu, 29 Aug 2019 11:20:07 -0000",
+		outRFC3339: "2019-08-29T11:20:07Z",
+	}, {
+		in:         "Thu, 29 Aug 2019 11:20:07 +0300",
+		outRFC3339: "2019-08-29T11:20:07+03:00",
+	}, {
+		in:         "Thu, 29 Aug 2019 11:20:07 +0330",
+		outRFC3339: "2019-08-29T11:20:07+03:30",
+	}} {
+		tcDesc := fmt.Sprintf("Test case #%d: %v", i, tc)
+
+		parsed, err := ParseRFC822(tc.in)
+		assert.NoError(t, err, tcDesc)
+		assert.Equal(t, tc.outRFC3339, parsed.Format(RFC3339), tcDesc)
+	}
+}
+
+func TestParseRFC822TwoDigitYear(t *testing.T) {
+	for i, tc := range []struct {
+		in         string
+		outRFC3339 string
+	}{{
+		in:         "Thu, 29 Aug 19 11:20:07 GMT",
+		outRFC3339: "2019-08-29T11:20:07Z",
+	}, {
+		in:         "Thu, 29 Aug 19 11:20:07 +0330",
+		outRFC3339: "2019-08-29T11:20:07+03:30",
+	}, {
+		in:         "Fri, 31 Dec 99 23:59:59 GMT",
+		outRFC3339: "1999-12-31T23:59:59Z",
+	}, {
+		in:         "Sat, 01 Jan 00 00:00:00 GMT",
+		outRFC3339: "2000-01-01T00:00:00Z",
+	}, {
+		in:         "Fri, 31 Dec 49 23:59:59 GMT",
+		outRFC3339: "2049-12-31T23:59:59Z",
+	}, {
+		in:         "Sun, 01 Jan 50 00:00:00 GMT",
+		outRFC3339: "1950-01-01T00:00:00Z",
+	}, {
+		in:         "Mon, 01 Jan 68 00:00:00 GMT",
+		outRFC3339: "1968-01-01T00:00:00Z",
+	}} {
+		tcDesc := fmt.Sprintf("Test case #%d: %v", i, tc)
+
+		parsed, err := ParseRFC822(tc.in)
+		assert.NoError(t, err, tcDesc)
+		assert.Equal(t, tc.outRFC3339, parsed.Format(RFC3339), tcDesc)
+
+		// Marshaled with a 4 digit year
+		var ts testStruct
+		assert.NoError(t, json.Unmarshal([]byte(fmt.Sprintf(`{"ts":"%s"}`, tc.in)), &ts), tcDesc)
+		assert.Equal(t, tc.outRFC3339, ts.Time.Format(RFC3339), tcDesc)
+	}
+}
+
+func TestParseRFC822NoWeekday(t *testing.T) {
+	for i, tc := range []struct {
+		in         string
+		outRFC3339 string
+		outRFC822  string
+	}{{
+		in:         "29 Aug 2019 11:20:07 GMT",
+		outRFC3339: "2019-08-29T11:20:07Z",
+		outRFC822:  "Thu, 29 Aug 2019 11:20:07 GMT",
+	}, {
+		in:         "29 Aug 2019 11:20:07 +0330",
+		outRFC3339: "2019-08-29T11:20:07+03:30",
+		outRFC822:  "Thu, 29 Aug 2019 11:20:07 +0330",
+	}, {
+		in:         "1 Sep 2019 08:00:00 -0000",
+		outRFC3339: "2019-09-01T08:00:00Z",
+		outRFC822:  "Sun, 01 Sep 2019 08:00:00 -0000",
+	}, {
+		// The standard form is unaffected
+		in:         "Thu, 29 Aug 2019 11:20:07 GMT",
+		outRFC3339: "2019-08-29T11:20:07Z",
+		outRFC822:  "Thu, 29 Aug 2019 11:20:07 GMT",
+	}} {
+		tcDesc := fmt.Sprintf("Test case #%d: %v", i, tc)
+
+		parsed, err := ParseRFC822(tc.in)
+		assert.NoError(t, err, tcDesc)
+		assert.Equal(t, tc.outRFC3339, parsed.Format(RFC3339), tcDesc)
+
+		// Marshaled in the canonical form with the day of the week
+		var ts testStruct
+		assert.NoError(t, json.Unmarshal([]byte(fmt.Sprintf(`{"ts":"%s"}`, tc.in)), &ts), tcDesc)
+		encoded, err := json.Marshal(&ts)
+		assert.NoError(t, err, tcDesc)
+		assert.Equal(t, fmt.Sprintf(`{"ts":"%s"}`, tc.outRFC822), string(encoded), tcDesc)
+	}
+}
+
+func TestParseRFC822Error(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		outError string
+	}{{
+		in:       "Thu, 29 Aug 2019 11:20:07",
+		outError: `parsing time "Thu, 29 Aug 2019 11:20:07" as "Mon, 02 Jan 2006 15:04:05 -0700": cannot parse "" as "-0700"`,
+	}, {
+		in:       "foo",
+		outError: `parsing time "foo" as "Mon, 02 Jan 2006 15:04:05 MST": cannot parse "foo" as "Mon"`,
+	}} {
+		parsed, err := ParseRFC822(tc.in)
+		assert.EqualError(t, err, tc.outError)
+		assert.True(t, parsed.IsZero())
+	}
+}
+
+func TestRFC822OrNil(t *testing.T) {
+	type omitStruct struct {
+		Time *RFC822Time `json:"ts,omitempty"`
+	}
+
+	encoded, err := json.Marshal(&omitStruct{Time: RFC822Time{}.OrNil()})
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(encoded))
+
+	rfc822Time := NewRFC822TimeFromUnix(1567077607)
+	encoded, err = json.Marshal(&omitStruct{Time: rfc822Time.OrNil()})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ts":"Thu, 29 Aug 2019 11:20:07 UTC"}`, string(encoded))
+
+	var out omitStruct
+	err = json.Unmarshal([]byte(`{}`), &out)
+	assert.NoError(t, err)
+	assert.Nil(t, out.Time)
+}
+
+func TestSetLocation(t *testing.T) {
+	moscow, err := LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+	assert.Equal(t, Local, DefaultLocation())
+
+	SetLocation(moscow)
+	defer SetLocation(nil)
+	assert.Equal(t, moscow, DefaultLocation())
+
+	defer Freeze(Date(2019, August, 29, 8, 20, 7, 0, UTC)).Unfreeze()
+	assert.Equal(t, "Thu, 29 Aug 2019 11:20:07 MSK", NowRFC822().String())
+
+	// Abbreviations are resolved against the location regardless of the local location
+	parsed, err := ParseRFC822("Thu, 29 Aug 2019 11:20:07 MSK")
+	assert.NoError(t, err)
+	assert.Equal(t, "2019-08-29T11:20:07+03:00", parsed.Format(RFC3339))
+
+	SetLocation(nil)
+	assert.Equal(t, Local, DefaultLocation())
+	assert.Equal(t, "Thu, 29 Aug 2019 08:20:07 UTC", NowRFC822().String())
+}
@@ -116,6 +116,24 @@ func (s *SystemSuite) TestNewTicker(c *C) {
 	}
 }
 
+func (s *SystemSuite) TestTickerReset(c *C) {
+	t := NewTicker(time.Hour)
+
+	// When
+	start := Now()
+	t.Reset(100 * time.Millisecond)
+
+	// Then
+	end := <-t.C()
+	if end.Sub(start) < 100*time.Millisecond {
+		c.Error("Sleep did not last long enough")
+	}
+	if end.Sub(start) > time.Second {
+		c.Error("Waited too long")
+	}
+	t.Stop()
+}
+
 func (s *SystemSuite) TestTick(c *C) {
 	start := Now()
 
@@ -0,0 +1,23 @@
+package clock
+
+import "time"
+
+// FreezeForTest freezes the package-global clock at `at` like Freeze, and
+// unfreezes it when the test and all its subtests complete, so a test can not
+// forget to restore the clock used by unrelated tests. The returned FrozenClock
+// is the one backing the package functions, advancing either advances both.
+//
+// `t` is usually a *testing.T or *testing.B, it is accepted as an interface such
+// that the testing package is not linked into binaries importing this package.
+//
+//	func TestSomething(t *testing.T) {
+//		clk := clock.FreezeForTest(t, time.Now())
+//		clk.Advance(time.Second)
+//	}
+func FreezeForTest(t interface{ Cleanup(func()) }, at time.Time) *FrozenClock {
+	fc := NewFrozenClock(at)
+	frozenAt = at.UTC()
+	provider = fc
+	t.Cleanup(Unfreeze)
+	return fc
+}
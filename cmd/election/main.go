@@ -39,12 +39,14 @@ func main() {
 
 	leaderChan := make(chan etcdutil.Event, 5)
 	e, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
-		Election:  "cli-election",
-		Candidate: os.Args[1],
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "cli-election",
+			Candidate: os.Args[1],
+			TTL:       5,
+		},
 		EventObserver: func(e etcdutil.Event) {
 			leaderChan <- e
 		},
-		TTL: 5,
 	})
 	if err != nil {
 		fmt.Printf("during election start: %s\n", err)
@@ -0,0 +1,98 @@
+package etcdutil
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/pkg/errors"
+)
+
+// Codec encodes our candidate value into the data stored in the campaign key, and
+// decodes the data of the current leader into the `Event.LeaderValue` given to observers.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte) (interface{}, error)
+}
+
+// JSONCodec is a Codec which encodes candidate values as JSON. Values are decoded
+// into a new value of the same type as `Value`, if `Value` is nil values are decoded
+// into a map[string]interface{}.
+//
+//	type Candidate struct {
+//		Host string `json:"host"`
+//		Zone string `json:"zone"`
+//	}
+//
+//	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+//		ElectionSettings: etcdutil.ElectionSettings{
+//			Election: "my-service",
+//		},
+//		Codec:          etcdutil.JSONCodec{Value: Candidate{}},
+//		CandidateValue: Candidate{Host: "worker-n01", Zone: "us-east-1a"},
+//		EventObserver: func(e etcdutil.Event) {
+//			if leader, ok := e.LeaderValue.(*Candidate); ok {
+//				fmt.Printf("Leader is in %s\n", leader.Zone)
+//			}
+//		},
+//	})
+type JSONCodec struct {
+	Value interface{}
+}
+
+func (c JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal returns a pointer to a new value of the type of `Value`
+func (c JSONCodec) Unmarshal(data []byte) (interface{}, error) {
+	if c.Value == nil {
+		var m map[string]interface{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
+	v := reflect.New(reflect.TypeOf(c.Value)).Interface()
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// PutJSON encodes `v` as JSON and stores it in `key`. Options such as etcd.WithLease()
+// are passed to the Put, such that the key expires with a lease.
+//
+//	lease, err := client.Grant(ctx, 10)
+//	if err != nil {
+//		return err
+//	}
+//	err = etcdutil.PutJSON(ctx, client, "/services/worker-n01", candidate, etcd.WithLease(lease.ID))
+func PutJSON(ctx context.Context, client *etcd.Client, key string, v interface{}, opts ...etcd.OpOption) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "while encoding value for '%s'", key)
+	}
+	if _, err := client.Put(ctx, key, string(b), opts...); err != nil {
+		return errors.Wrapf(err, "while putting '%s'", key)
+	}
+	return nil
+}
+
+// GetJSON decodes the JSON stored in `key` into `v`, and returns false if the key does
+// not exist in which case `v` is left untouched.
+func GetJSON(ctx context.Context, client *etcd.Client, key string, v interface{}) (bool, error) {
+	resp, err := client.Get(ctx, key)
+	if err != nil {
+		return false, errors.Wrapf(err, "while getting '%s'", key)
+	}
+	if len(resp.Kvs) == 0 {
+		return false, nil
+	}
+	if err := json.Unmarshal(resp.Kvs[0].Value, v); err != nil {
+		return true, errors.Wrapf(err, "while decoding value of '%s'", key)
+	}
+	return true, nil
+}
@@ -0,0 +1,75 @@
+package etcdutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/mailgun/holster/etcdutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type candidate struct {
+	Host string `json:"host"`
+	Zone string `json:"zone"`
+}
+
+func TestJSONCodec(t *testing.T) {
+	codec := etcdutil.JSONCodec{Value: candidate{}}
+
+	b, err := codec.Marshal(candidate{Host: "worker-n01", Zone: "us-east-1a"})
+	require.Nil(t, err)
+	assert.Equal(t, `{"host":"worker-n01","zone":"us-east-1a"}`, string(b))
+
+	v, err := codec.Unmarshal(b)
+	require.Nil(t, err)
+	assert.Equal(t, &candidate{Host: "worker-n01", Zone: "us-east-1a"}, v)
+
+	// Without a Value, data is decoded into a map
+	v, err = etcdutil.JSONCodec{}.Unmarshal(b)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"host": "worker-n01", "zone": "us-east-1a"}, v)
+
+	_, err = codec.Unmarshal([]byte("not json"))
+	assert.NotNil(t, err)
+}
+
+func TestPutGetJSON(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	lease, err := client.Grant(ctx, 10)
+	require.Nil(t, err)
+	defer client.Revoke(context.Background(), lease.ID)
+
+	expected := candidate{Host: "worker-n01", Zone: "us-east-1a"}
+	err = etcdutil.PutJSON(ctx, client, "/json/worker-n01", expected, etcd.WithLease(lease.ID))
+	require.Nil(t, err)
+
+	var got candidate
+	found, err := etcdutil.GetJSON(ctx, client, "/json/worker-n01", &got)
+	require.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, expected, got)
+
+	// The key is bound to the lease
+	resp, err := client.Get(ctx, "/json/worker-n01")
+	require.Nil(t, err)
+	require.Len(t, resp.Kvs, 1)
+	assert.Equal(t, int64(lease.ID), resp.Kvs[0].Lease)
+
+	var missing candidate
+	found, err = etcdutil.GetJSON(ctx, client, "/json/does-not-exist", &missing)
+	require.Nil(t, err)
+	assert.False(t, found)
+	assert.Equal(t, candidate{}, missing)
+
+	_, err = client.Put(ctx, "/json/invalid", "not json")
+	require.Nil(t, err)
+	found, err = etcdutil.GetJSON(ctx, client, "/json/invalid", &got)
+	require.NotNil(t, err)
+	assert.True(t, found)
+	assert.Contains(t, err.Error(), "while decoding value of '/json/invalid'")
+}
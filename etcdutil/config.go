@@ -1,6 +1,7 @@
 package etcdutil
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"io/ioutil"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/clientv3/namespace"
 	"github.com/mailgun/holster"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/grpclog"
@@ -26,7 +28,8 @@ func init() {
 }
 
 // NewClient creates a new etcd.Client with the specified config where blanks
-// are filled from environment variables by NewConfig.
+// are filled from environment variables by NewConfig. Fields set explicitly
+// in the provided config always take precedence over environment variables.
 //
 // If the provided config is nil and no environment variables are set, it will
 // return a client connecting without TLS via localhost:2379.
@@ -43,10 +46,92 @@ func NewClient(cfg *etcd.Config) (*etcd.Client, error) {
 	return etcdClt, nil
 }
 
+// NewNamespacedClient is just like NewClient but all keys, leases and watches made
+// through the returned client are transparently prefixed with `prefix`. This isolates
+// applications sharing a cluster, elections and sessions created with the client
+// operate within the namespace without knowing about the prefix.
+//
+//	// The key "/elections/my-service" is stored as "/my-app/elections/my-service"
+//	client, err := etcdutil.NewNamespacedClient(nil, "/my-app")
+func NewNamespacedClient(cfg *etcd.Config, prefix string) (*etcd.Client, error) {
+	if prefix == "" {
+		return nil, errors.New("namespace prefix can not be empty")
+	}
+
+	etcdClt, err := NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	etcdClt.KV = namespace.NewKV(etcdClt.KV, prefix)
+	etcdClt.Watcher = namespace.NewWatcher(etcdClt.Watcher, prefix)
+	etcdClt.Lease = namespace.NewLease(etcdClt.Lease, prefix)
+	return etcdClt, nil
+}
+
+// NewClientWithReady is just like NewClient but only returns once the cluster
+// has responded to a status request. Connection attempts are retried with
+// back off until the cluster is reachable or the provided context is cancelled.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//
+//	// Blocks until etcd is reachable or a minute has passed
+//	client, err := etcdutil.NewClientWithReady(ctx, nil)
+func NewClientWithReady(ctx context.Context, cfg *etcd.Config) (*etcd.Client, error) {
+	var err error
+	if cfg, err = NewConfig(cfg); err != nil {
+		return nil, errors.Wrap(err, "failed to build etcd config")
+	}
+
+	backOff := holster.NewBackOff(time.Millisecond*500, cfg.DialTimeout, 2)
+	for {
+		var etcdClt *etcd.Client
+		if etcdClt, err = etcd.New(*cfg); err == nil {
+			if err = waitForReady(ctx, etcdClt, cfg.DialTimeout); err == nil {
+				return etcdClt, nil
+			}
+			etcdClt.Close()
+		}
+
+		select {
+		case <-time.After(backOff.Next()):
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "while waiting for etcd to become ready: %s", err)
+		}
+	}
+}
+
+// waitForReady returns nil if any of the endpoints responds to a status request
+func waitForReady(ctx context.Context, client *etcd.Client, timeout time.Duration) error {
+	var err error
+	for _, endpoint := range client.Endpoints() {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		_, err = client.Status(ctx, endpoint)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
 // NewConfig creates a new etcd.Config using environment variables. If an
 // existing config is passed, it will fill in missing configuration using
 // environment variables or defaults if they exists on the local system.
 //
+// Precedence is: explicit config, then environment, then defaults. The
+// following environment variables are honored
+//
+//	ETCD3_ENDPOINT     - comma separated list of endpoints
+//	ETCD3_USER         - username
+//	ETCD3_PASSWORD     - password
+//	ETCD3_DIAL_TIMEOUT - dial timeout as a duration (1m|15s|24h)
+//	ETCD3_TLS_CERT     - path to the client TLS certificate
+//	ETCD3_TLS_KEY      - path to the client TLS key
+//	ETCD3_CA           - path to the TLS certificate authority
+//	ETCD3_SKIP_VERIFY  - connect with TLS but skip cert verification
+//	ETCD3_ENABLE_TLS   - connect with TLS using the system defaults
+//
 // If no environment variables are set, it will return a config set to
 // connect without TLS via localhost:2379.
 func NewConfig(cfg *etcd.Config) (*etcd.Config, error) {
@@ -59,10 +144,8 @@ func NewConfig(cfg *etcd.Config) (*etcd.Config, error) {
 	holster.SetDefault(&tlsKeyFile, os.Getenv("ETCD3_TLS_KEY"))
 	holster.SetDefault(&tlsCAFile, os.Getenv("ETCD3_CA"))
 
-	// Default to 5 second timeout, else connections hang indefinitely
-	holster.SetDefault(&cfg.DialTimeout, time.Second*5)
-	// Or if the user provided a timeout
-	if timeout := os.Getenv("ETCD3_DIAL_TIMEOUT"); timeout != "" {
+	// If the user provided a timeout and the config didn't
+	if timeout := os.Getenv("ETCD3_DIAL_TIMEOUT"); timeout != "" && cfg.DialTimeout == 0 {
 		duration, err := time.ParseDuration(timeout)
 		if err != nil {
 			return nil, errors.Errorf(
@@ -70,6 +153,8 @@ func NewConfig(cfg *etcd.Config) (*etcd.Config, error) {
 		}
 		cfg.DialTimeout = duration
 	}
+	// Default to 5 second timeout, else connections hang indefinitely
+	holster.SetDefault(&cfg.DialTimeout, time.Second*5)
 
 	// If the CA file was provided
 	if tlsCAFile != "" {
@@ -0,0 +1,148 @@
+package etcdutil_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/mailgun/holster/etcdutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setEnv(t *testing.T, env map[string]string) func() {
+	for k, v := range env {
+		require.Nil(t, os.Setenv(k, v))
+	}
+	return func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestNewConfigFromEnv(t *testing.T) {
+	defer setEnv(t, map[string]string{
+		"ETCD3_ENDPOINT":     "etcd-n01:2379,etcd-n02:2379",
+		"ETCD3_USER":         "root",
+		"ETCD3_PASSWORD":     "rootpw",
+		"ETCD3_DIAL_TIMEOUT": "15s",
+	})()
+
+	cfg, err := etcdutil.NewConfig(nil)
+	require.Nil(t, err)
+
+	assert.Equal(t, []string{"etcd-n01:2379", "etcd-n02:2379"}, cfg.Endpoints)
+	assert.Equal(t, "root", cfg.Username)
+	assert.Equal(t, "rootpw", cfg.Password)
+	assert.Equal(t, time.Second*15, cfg.DialTimeout)
+	assert.Nil(t, cfg.TLS)
+}
+
+func TestNewConfigExplicitWins(t *testing.T) {
+	defer setEnv(t, map[string]string{
+		"ETCD3_ENDPOINT":     "etcd-n01:2379",
+		"ETCD3_USER":         "root",
+		"ETCD3_PASSWORD":     "rootpw",
+		"ETCD3_DIAL_TIMEOUT": "15s",
+	})()
+
+	cfg, err := etcdutil.NewConfig(&etcd.Config{
+		Endpoints:   []string{"explicit:2379"},
+		Username:    "admin",
+		DialTimeout: time.Second,
+	})
+	require.Nil(t, err)
+
+	assert.Equal(t, []string{"explicit:2379"}, cfg.Endpoints)
+	assert.Equal(t, "admin", cfg.Username)
+	// Blanks are still filled from the environment
+	assert.Equal(t, "rootpw", cfg.Password)
+	assert.Equal(t, time.Second, cfg.DialTimeout)
+}
+
+func TestNewConfigDefaults(t *testing.T) {
+	cfg, err := etcdutil.NewConfig(nil)
+	require.Nil(t, err)
+
+	assert.Equal(t, []string{"127.0.0.1:2379"}, cfg.Endpoints)
+	assert.Equal(t, time.Second*5, cfg.DialTimeout)
+	assert.Nil(t, cfg.TLS)
+}
+
+func TestNewConfigBadDialTimeout(t *testing.T) {
+	defer setEnv(t, map[string]string{
+		"ETCD3_DIAL_TIMEOUT": "foo",
+	})()
+
+	_, err := etcdutil.NewConfig(nil)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ETCD3_DIAL_TIMEOUT='foo' is not a duration")
+}
+
+func TestNewClientWithReady(t *testing.T) {
+	// Simulate etcd starting some time after our service
+	proxy.Stop()
+	started := make(chan error)
+	go func() {
+		time.Sleep(time.Second)
+		started <- proxy.Start()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	start := time.Now()
+	c, err := etcdutil.NewClientWithReady(ctx, nil)
+	require.Nil(t, err)
+	defer c.Close()
+
+	require.Nil(t, <-started)
+	assert.True(t, time.Since(start) >= time.Second)
+
+	_, err = c.Get(ctx, "/ready")
+	assert.Nil(t, err)
+}
+
+func TestNewClientWithReadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := etcdutil.NewClientWithReady(ctx, &etcd.Config{
+		Endpoints:   []string{"127.0.0.1:1"},
+		DialTimeout: time.Millisecond * 100,
+	})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "while waiting for etcd to become ready")
+}
+
+func TestNewNamespacedClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	c, err := etcdutil.NewNamespacedClient(nil, "/namespace-test")
+	require.Nil(t, err)
+	defer c.Close()
+
+	_, err = c.Put(ctx, "/key", "value")
+	require.Nil(t, err)
+
+	// The raw client sees the prefixed key
+	resp, err := client.Get(ctx, "/namespace-test/key")
+	require.Nil(t, err)
+	require.Equal(t, int64(1), resp.Count)
+	assert.Equal(t, "value", string(resp.Kvs[0].Value))
+
+	// The namespaced client does not see keys outside the namespace
+	resp, err = c.Get(ctx, "/namespace-test/key")
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), resp.Count)
+}
+
+func TestNewNamespacedClientEmptyPrefix(t *testing.T) {
+	_, err := etcdutil.NewNamespacedClient(nil, "")
+	require.NotNil(t, err)
+	assert.Equal(t, "namespace prefix can not be empty", err.Error())
+}
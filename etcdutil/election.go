@@ -3,21 +3,34 @@ package etcdutil
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	etcd "github.com/coreos/etcd/clientv3"
 	"github.com/coreos/etcd/mvcc/mvccpb"
 	"github.com/mailgun/holster"
+	"github.com/mailgun/holster/clock"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
 var log *logrus.Entry
 
+// Separates the candidate data from the heartbeat timestamp appended to the campaign
+// value of a leader. Candidate data may not contain it, such that the value of a
+// candidate without heartbeats is never mistaken for one with a heartbeat.
+const heartbeatSeparator = "\x00heartbeat="
+
 type LeaderElector interface {
 	IsLeader() bool
 	Concede() (bool, error)
@@ -36,88 +49,424 @@ type Event struct {
 	LeaderKey string
 	// Hold the current leaders data
 	LeaderData string
+	// Holds the current leaders data decoded by ElectionConfig.Codec,
+	// nil if no Codec was provided.
+	LeaderValue interface{}
 	// If not nil, contains an error encountered
 	// while participating in the election.
 	Err error
+	// If not empty, describes an unexpected condition which did not prevent us
+	// from participating in the election. Only Warn is set on such events.
+	Warn string
 }
 
+// EventObserver is called with every election event. Observers are called one at a
+// time in the order events occur and may add or remove observers, but an observer which
+// blocks delays every event after it. As such an observer must not wait for a future
+// event, for instance by calling WaitForLeader() for a candidate which is not yet leader
+// or RunAsLeader(), since that event can not be delivered until the observer returns.
 type EventObserver func(Event)
 
 type Election struct {
-	observers map[string]EventObserver
-	backOff   *holster.BackOffCounter
-	cancel    context.CancelFunc
-	wg        holster.WaitGroup
-	ctx       context.Context
-	conf      ElectionConfig
-	timeout   time.Duration
-	client    *etcd.Client
-	session   *Session
-	key       string
-	isLeader  int32
-	isRunning bool
+	// Serializes the delivery of events to observers
+	notifyMutex    sync.Mutex
+	observersMutex sync.Mutex
+	observers      map[string]EventObserver
+	backOff        *holster.BackOffCounter
+	cancel         context.CancelFunc
+	wg             holster.WaitGroup
+	ctx            context.Context
+	conf           ElectionConfig
+	client         *etcd.Client
+	session        *Session
+	key            string
+	isLeader       int32
+	sessionResets  int64
+	duplicateKeys  int64
+	waiters        int64
+	isRunning      bool
+	tracer         Tracer
+	// Protects the fields reported by Stats()
+	mutex      sync.Mutex
+	leaderKey  string
+	leaderData string
+	// The time of the last heartbeat of the leader
+	leaderHeartbeat time.Time
+	// The leader data decoded by the Codec
+	leaderValue interface{}
+	leaseID     etcd.LeaseID
+	// The revision at which our campaign was registered
+	revision int64
+	// The time before which we do not campaign again after conceding
+	cooldownUntil time.Time
+	// The time the election was created and the time we first became leader
+	createdAt     time.Time
+	firstLeaderAt time.Time
+	// Closed once the election has shut down
+	done      chan struct{}
+	closeOnce sync.Once
+	// Guards the call to ElectionConfig.OnFirstLeader
+	firstLeaderOnce sync.Once
 }
 
-type ElectionConfig struct {
-	// Optional function when provided is called every time leadership changes or an error occurs
-	EventObserver EventObserver
+// Candidate is a campaign registered in the election as given to ElectionConfig.LeaderPolicy
+type Candidate struct {
+	// The campaign key of the candidate
+	Key string
+	// The data of the candidate, see Event.LeaderData
+	Data string
+	// The data of the candidate decoded by the Codec, see Event.LeaderValue
+	Value interface{}
+	// The priority the candidate campaigns with
+	Priority int
+	// The revision at which the campaign was registered
+	CreateRevision int64
+}
+
+// ElectionStats is a snapshot of the state of an Election
+type ElectionStats struct {
+	// True if our candidate is leader
+	IsLeader bool
+	// True if our candidate is participating in the election
+	IsRunning bool
+	// The key and data of the current leader
+	CurrentLeaderKey  string
+	CurrentLeaderData string
+	// The key our candidate registered for the campaign
+	CampaignKey string
+	// The lease currently held by the session, or NoLease
+	SessionLeaseID etcd.LeaseID
+	// The number of consecutive failed campaign attempts
+	BackoffAttempts int
+	// The number of times the session was reset after a fatal error
+	SessionResets int64
+	// The number of times our campaign key unexpectedly existed when registering
+	// the campaign, which indicates etcd reused a lease ID
+	DuplicateCampaignKeys int64
+}
+
+// ElectionSettings holds the fields of ElectionConfig which can be stored in a config
+// file, durations are encoded as strings (IE: "5s"), see LoadElectionConfig()
+type ElectionSettings struct {
 	// The name of the election (IE: scout, blackbird, etc...)
-	Election string
+	Election string `json:"election,omitempty"`
 	// The name of this instance (IE: worker-n01, worker-n02, etc...)
-	Candidate string
+	Candidate string `json:"candidate,omitempty"`
 	// Seconds to wait before giving up the election if leader disconnected
-	TTL int64
+	TTL int64 `json:"ttl,omitempty"`
+	// The maximum time to wait for each etcd request made while registering, watching
+	// or withdrawing the campaign, such that a request stuck on an unresponsive etcd
+	// is abandoned and retried. (Default is TTL)
+	OpTimeout time.Duration `json:"op_timeout,omitempty"`
+	// Candidates with a lower priority number are preferred as leader regardless of when
+	// they joined the election, and will take leadership from a current leader with a
+	// higher priority number. Among candidates of equal priority the first to join wins.
+	// When all candidates use the default of 0 the first candidate to join wins as before.
+	// Versions before Priority only agree on the leader while every candidate uses 0, so
+	// a priority must not be set until all candidates are upgraded.
+	Priority int `json:"priority,omitempty"`
+	// If true, Close() does not delete our campaign key or revoke our lease, which
+	// could block or fail if etcd is unreachable during shutdown. Instead the key
+	// is left to expire with our lease, so if we were leader no other candidate
+	// can become leader for up to TTL seconds after Close() returns.
+	FastClose bool `json:"fast_close,omitempty"`
+	// The maximum time NewElection() waits to determine the current leader before
+	// giving up and returning context.DeadlineExceeded. Zero means NewElection()
+	// waits until the context it was given is done.
+	InitialTimeout time.Duration `json:"initial_timeout,omitempty"`
+	// If true, NewElection() returns immediately instead of waiting to determine the
+	// current leader, so a service can start while etcd is unreachable. IsLeader()
+	// returns false until leadership is acquired, and the EventObserver receives
+	// events as the election progresses.
+	NonBlocking bool `json:"non_blocking,omitempty"`
+	// If not zero, while we are leader our campaign value is rewritten with the current
+	// time on this interval, such that observers can detect a leader which holds the lease
+	// but has stopped functioning, see Election.LeaderHeartbeat().
+	HeartbeatInterval time.Duration `json:"heartbeat_interval,omitempty"`
+	// Optional campaign key and lease of a previous instance of this candidate, as reported
+	// by Stats().CampaignKey and Stats().SessionLeaseID, for a process restarting quickly
+	// to reclaim its campaign, and the leadership it held, before the lease expires. If the
+	// lease has expired a new campaign is registered as usual.
+	ResumeKey   string       `json:"resume_key,omitempty"`
+	ResumeLease etcd.LeaseID `json:"resume_lease,omitempty"`
+	// The time to wait after Concede() before registering our campaign again, such that
+	// another candidate can take leadership instead of us immediately winning it back.
+	// During the cooldown we are a follower and receive no leadership events.
+	ConcedeCooldown time.Duration `json:"concede_cooldown,omitempty"`
 }
 
-// NewElection creates a new leader election and submits our candidate for leader.
-//
-//  client, _ := etcdutil.NewClient(nil)
+type ElectionConfig struct {
+	// The fields which can be encoded to and from JSON, callbacks and other fields
+	// below are not encoded and must be set in code.
+	ElectionSettings
+	// Optional function when provided is called every time leadership changes or an error occurs
+	EventObserver EventObserver
+	// Optional function which provides the Candidate when it is empty, such as
+	// one returning the pod name (Default is os.Hostname)
+	HostnameFunc func() (string, error)
+	// Optional codec used to encode CandidateValue and decode the data of the
+	// leader into Event.LeaderValue, see JSONCodec.
+	Codec Codec
+	// Optional value encoded by Codec and used as the candidate data in place of Candidate
+	CandidateValue interface{}
+	// Optional clock used to schedule and timestamp heartbeats, to time the
+	// ConcedeCooldown and to measure Election.TimeToLeadership() (Default is clock.NewRealClock())
+	Clock clock.Clock
+	// Optional function called with the campaign key and the lease it is attached to every
+	// time the campaign is registered, including re-registration after a session reset.
+	OnCampaign func(key string, lease etcd.LeaseID)
+	// Optional function called only the first time our candidate becomes leader, such as
+	// to run one time initialization like database migrations. It is not called again when
+	// leadership is lost and reacquired. It is called before the EventObserver is notified
+	// and blocks the election from observing further leadership changes until it returns.
+	OnFirstLeader func()
+	// Optional function called with the revision the watch begins after, every time the
+	// watch of the election is established, including after the watch fails and is
+	// re-established. Frequent calls indicate the watch is flapping. It is called from
+	// the watch routine and should not block.
+	OnWatchStart func(rev int64)
+	// Optional function consulted before registering our campaign, while it returns false
+	// our candidate does not campaign and it is consulted again with backoff, such that a
+	// node still warming up does not become leader. Unless NonBlocking is set NewElection()
+	// waits until it returns true and the current leader is determined.
+	ReadyFunc func() bool
+	// Optional function which selects the leader from the candidates of the election in
+	// place of the default rule, where the candidate with the lowest Priority that joined
+	// first wins. It is called every time the campaign keys change and must select the same
+	// leader on every candidate, so all candidates must use the same policy. If it returns
+	// nil the default rule applies.
+	LeaderPolicy func(candidates []Candidate) *Candidate
+	// Optional tracer which receives a span for each campaign registration, watch and
+	// withdrawal. Leadership transitions are recorded as events on the watch span.
+	Tracer Tracer
+}
+
+func (c ElectionConfig) hostname() (string, error) {
+	if c.HostnameFunc != nil {
+		return c.HostnameFunc()
+	}
+	return os.Hostname()
+}
+
+// Validate checks the config for errors NewElection would encounter without contacting
+// etcd. All problems found are reported in a single error.
+func (c ElectionConfig) Validate() error {
+	var errs []string
+	if c.Election == "" {
+		errs = append(errs, "ElectionConfig.Election can not be empty")
+	} else if strings.Contains(c.Election, "..") {
+		errs = append(errs, fmt.Sprintf("ElectionConfig.Election '%s' can not contain '..'", c.Election))
+	}
+	if c.TTL < 0 {
+		errs = append(errs, fmt.Sprintf("ElectionConfig.TTL '%d' can not be negative", c.TTL))
+	}
+	if c.OpTimeout < 0 {
+		errs = append(errs, fmt.Sprintf("ElectionConfig.OpTimeout '%s' can not be negative", c.OpTimeout))
+	}
+	if c.HeartbeatInterval < 0 {
+		errs = append(errs, fmt.Sprintf("ElectionConfig.HeartbeatInterval '%s' can not be negative", c.HeartbeatInterval))
+	}
+	if c.ConcedeCooldown < 0 {
+		errs = append(errs, fmt.Sprintf("ElectionConfig.ConcedeCooldown '%s' can not be negative", c.ConcedeCooldown))
+	}
+	if c.InitialTimeout < 0 {
+		errs = append(errs, fmt.Sprintf("ElectionConfig.InitialTimeout '%s' can not be negative", c.InitialTimeout))
+	}
+	if c.Priority < 0 {
+		errs = append(errs, fmt.Sprintf("ElectionConfig.Priority '%d' can not be negative", c.Priority))
+	}
+	if c.ResumeKey != "" && (c.ResumeLease == 0 || c.ResumeLease == NoLease) {
+		errs = append(errs, "ElectionConfig.ResumeKey requires a ResumeLease")
+	}
+	if strings.Contains(c.Candidate, heartbeatSeparator) {
+		errs = append(errs, fmt.Sprintf("ElectionConfig.Candidate can not contain %q", heartbeatSeparator))
+	}
+	if c.CandidateValue != nil && c.Codec == nil {
+		errs = append(errs, "ElectionConfig.CandidateValue requires a Codec")
+	}
+	if c.Candidate == "" && c.CandidateValue == nil {
+		if _, err := c.hostname(); err != nil {
+			errs = append(errs, fmt.Sprintf("ElectionConfig.Candidate is empty and hostname is unavailable: %s", err))
+		}
+	}
+	if len(errs) != 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// MarshalJSON encodes the settings with durations as strings (IE: "5s"). As the method is
+// promoted to ElectionConfig only the settings of an ElectionConfig are encoded.
+func (s ElectionSettings) MarshalJSON() ([]byte, error) {
+	type settings ElectionSettings
+	b, err := json.Marshal(settings(s))
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for name, d := range s.durations() {
+		if _, ok := fields[name]; ok {
+			if fields[name], err = json.Marshal(d.String()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON decodes settings encoded by MarshalJSON, durations may also be given in
+// nanoseconds. Fields of an ElectionConfig which are not settings are left untouched.
+func (s *ElectionSettings) UnmarshalJSON(b []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	for name := range s.durations() {
+		var value string
+		if err := json.Unmarshal(fields[name], &value); err != nil {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.Errorf("%s='%s' is not a duration (1m|15s|24h): %s", name, value, err)
+		}
+		if fields[name], err = json.Marshal(int64(d)); err != nil {
+			return err
+		}
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	type settings ElectionSettings
+	return json.Unmarshal(b, (*settings)(s))
+}
+
+// durations returns the duration fields of the settings by their JSON name
+func (s ElectionSettings) durations() map[string]time.Duration {
+	v := reflect.ValueOf(s)
+	durations := make(map[string]time.Duration)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Type != durationType {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		durations[name] = time.Duration(v.Field(i).Int())
+	}
+	return durations
+}
+
+// LoadElectionConfig reads a JSON encoded ElectionConfig from the reader and validates
+// it. Callbacks such as EventObserver are not part of the encoding and can be set on
+// the returned config before it is passed to NewElection().
 //
-//  // Start a leader election and attempt to become leader, only returns after
-//  // determining the current leader.
-//  election := etcdutil.NewElection(client, etcdutil.ElectionConfig{
-//      Election: "presidental",
-//      Candidate: "donald",
-//		EventObserver: func(e etcdutil.Event) {
-//		  	fmt.Printf("Leader Data: %t\n", e.LeaderData)
-//			if e.IsLeader {
-//				// Do thing as leader
-//			}
-//		},
-//      TTL: 5,
-//  })
+//	f, err := os.Open("election.json")
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
 //
-//	// Returns true if we are leader (thread safe)
-//	if election.IsLeader() {
-//		// Do periodic thing
+//	conf, err := etcdutil.LoadElectionConfig(f)
+//	if err != nil {
+//		return err
 //	}
+//	conf.EventObserver = func(e etcdutil.Event) {
+//		// Handle leadership changes
+//	}
+//	election, err := etcdutil.NewElection(ctx, client, conf)
+func LoadElectionConfig(r io.Reader) (ElectionConfig, error) {
+	var conf ElectionConfig
+	if err := json.NewDecoder(r).Decode(&conf); err != nil {
+		return ElectionConfig{}, errors.Wrap(err, "while decoding election config")
+	}
+	if err := conf.Validate(); err != nil {
+		return ElectionConfig{}, err
+	}
+	return conf, nil
+}
+
+// NewElection creates a new leader election and submits our candidate for leader.
+//
+//	 client, _ := etcdutil.NewClient(nil)
+//
+//	 // Start a leader election and attempt to become leader, only returns after
+//	 // determining the current leader unless ElectionConfig.NonBlocking is set.
+//	 election := etcdutil.NewElection(client, etcdutil.ElectionConfig{
+//	     ElectionSettings: etcdutil.ElectionSettings{
+//	         Election: "presidental",
+//	         Candidate: "donald",
+//	         TTL: 5,
+//	     },
+//			EventObserver: func(e etcdutil.Event) {
+//			  	fmt.Printf("Leader Data: %t\n", e.LeaderData)
+//				if e.IsLeader {
+//					// Do thing as leader
+//				}
+//			},
+//	 })
 //
-//  // Concede the election if leader and cancel our candidacy
-//  // for the election.
-//  election.Close()
+//		// Returns true if we are leader (thread safe)
+//		if election.IsLeader() {
+//			// Do periodic thing
+//		}
 //
+//	 // Concede the election if leader and cancel our candidacy
+//	 // for the election.
+//	 election.Close()
 func NewElection(ctx context.Context, client *etcd.Client, conf ElectionConfig) (*Election, error) {
-	if conf.Election == "" {
-		return nil, errors.New("ElectionConfig.Election can not be empty")
+	if err := conf.Validate(); err != nil {
+		return nil, err
 	}
 
 	log = logrus.WithField("category", "election")
 
 	// Default to short 5 second leadership TTL
 	holster.SetDefault(&conf.TTL, int64(5))
+	holster.SetDefault(&conf.OpTimeout, time.Duration(conf.TTL)*time.Second)
+	if conf.Clock == nil {
+		conf.Clock = clock.NewRealClock()
+	}
 	conf.Election = path.Join("/elections", conf.Election)
+	if conf.ResumeKey != "" && !strings.HasPrefix(conf.ResumeKey, conf.Election) {
+		return nil, errors.Errorf("ElectionConfig.ResumeKey '%s' is not a key of election '%s'",
+			conf.ResumeKey, conf.Election)
+	}
+
+	if conf.CandidateValue != nil {
+		b, err := conf.Codec.Marshal(conf.CandidateValue)
+		if err != nil {
+			return nil, errors.Wrap(err, "while encoding ElectionConfig.CandidateValue")
+		}
+		if bytes.Contains(b, []byte(heartbeatSeparator)) {
+			return nil, errors.Errorf("encoded ElectionConfig.CandidateValue can not contain %q", heartbeatSeparator)
+		}
+		conf.Candidate = string(b)
+	}
 
 	// Use the hostname if no candidate name provided
-	if host, err := os.Hostname(); err == nil {
+	if host, err := conf.hostname(); err == nil {
 		holster.SetDefault(&conf.Candidate, host)
 	}
 
 	e := &Election{
 		backOff:   holster.NewBackOff(time.Millisecond*500, time.Duration(conf.TTL)*time.Second, 2),
-		timeout:   time.Duration(conf.TTL) * time.Second,
 		observers: make(map[string]EventObserver),
+		done:      make(chan struct{}),
 		client:    client,
 		conf:      conf,
+		tracer:    conf.Tracer,
+		createdAt: conf.Clock.Now(),
+	}
+	if e.tracer == nil {
+		e.tracer = noopTracer{}
 	}
 
 	e.ctx, e.cancel = context.WithCancel(context.Background())
@@ -129,28 +478,49 @@ func NewElection(ctx context.Context, client *etcd.Client, conf ElectionConfig)
 
 	var err error
 	ready := make(chan struct{})
-	// Register ourselves as an observer for the initial election, then remove before returning
-	e.observers["init"] = func(event Event) {
-		// If we get an error while waiting on the election results, pass that back to the caller
-		if event.Err != nil {
-			err = event.Err
+	if !conf.NonBlocking {
+		// Register ourselves as an observer for the initial election, then remove before returning
+		e.observers["init"] = func(event Event) {
+			// Warnings do not tell us the results of the election
+			if event.Warn != "" {
+				return
+			}
+			// If we get an error while waiting on the election results, pass that back to the caller
+			if event.Err != nil {
+				err = event.Err
+			}
+			e.RemoveObserver("init")
+			close(ready)
 		}
-		delete(e.observers, "init")
-		close(ready)
 	}
 
 	// Create a new Session
 	if e.session, err = NewSession(e.client, SessionConfig{
-		Observer: e.onSessionChange,
-		TTL:      e.conf.TTL,
+		Observer:    e.onSessionChange,
+		TTL:         e.conf.TTL,
+		FastClose:   e.conf.FastClose,
+		ResumeLease: e.conf.ResumeLease,
 	}); err != nil {
 		return nil, err
 	}
 
+	// Leadership is resolved in the background and reported to the observers
+	if conf.NonBlocking {
+		return e, nil
+	}
+
+	if conf.InitialTimeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, conf.InitialTimeout)
+		defer cancel()
+	}
+
 	// Wait for results of leader election
 	select {
 	case <-ready:
 	case <-ctx.Done():
+		// Stop attempting to join the election
+		e.Close()
 		return nil, ctx.Err()
 	}
 	return e, err
@@ -158,15 +528,18 @@ func NewElection(ctx context.Context, client *etcd.Client, conf ElectionConfig)
 
 func (e *Election) onSessionChange(leaseID etcd.LeaseID, err error) {
 	//log.Debugf("SessionChange: Lease ID: %v running: %t err: %v", leaseID, e.isRunning, err)
+	e.mutex.Lock()
+	e.leaseID = leaseID
+	e.mutex.Unlock()
 
 	// If we lost our lease, concede the campaign and stop
 	if leaseID == NoLease {
 		// Avoid stopping twice
-		if !e.isRunning {
+		if !e.running() {
 			return
 		}
 		e.wg.Stop()
-		e.isRunning = false
+		e.setRunning(false)
 		atomic.StoreInt32(&e.isLeader, 0)
 		if err != nil {
 			e.onErr(err, "lease error")
@@ -174,16 +547,38 @@ func (e *Election) onSessionChange(leaseID etcd.LeaseID, err error) {
 		return
 	}
 
-	if e.isRunning {
+	if e.running() {
 		return
 	}
 
-	e.isRunning = true
+	e.setRunning(true)
 
 	e.wg.Until(func(done chan struct{}) bool {
 		var err error
 		var rev int64
 
+		// If we recently conceded, give another candidate a chance to become leader
+		if d := e.cooldown(); d > 0 {
+			select {
+			case <-e.conf.Clock.After(d):
+			case <-done:
+				e.setRunning(false)
+				return false
+			}
+		}
+
+		// Do not campaign until our candidate is ready to lead
+		if e.conf.ReadyFunc != nil && !e.conf.ReadyFunc() {
+			log.WithField("election", e.conf.Election).Debug("candidate not ready, deferring campaign")
+			select {
+			case <-time.After(e.backOff.Next()):
+				return true
+			case <-done:
+				e.setRunning(false)
+				return false
+			}
+		}
+
 		rev, err = e.registerCampaign(leaseID)
 		if err != nil {
 			e.onErr(err, "during campaign registration")
@@ -191,7 +586,7 @@ func (e *Election) onSessionChange(leaseID etcd.LeaseID, err error) {
 			case <-time.After(e.backOff.Next()):
 				return true
 			case <-done:
-				e.isRunning = false
+				e.setRunning(false)
 				return false
 			}
 		}
@@ -204,14 +599,8 @@ func (e *Election) onSessionChange(leaseID etcd.LeaseID, err error) {
 			case <-done:
 			}
 
-			// If delete takes longer than our TTL then lease is expired
-			// and we are no longer leader anyway.
-			ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
 			// Withdraw our candidacy since an error occurred
-			if err := e.withDrawCampaign(ctx); err != nil {
-				e.onErr(err, "")
-			}
-			cancel()
+			e.closeCampaign()
 			return true
 		}
 		e.backOff.Reset()
@@ -219,22 +608,70 @@ func (e *Election) onSessionChange(leaseID etcd.LeaseID, err error) {
 	})
 }
 
+// cooldown returns the time remaining before we may campaign again after conceding
+func (e *Election) cooldown() time.Duration {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.cooldownUntil.Sub(e.conf.Clock.Now())
+}
+
+// closeCampaign withdraws our candidacy when the campaign stops. If FastClose is set
+// the campaign key is left to expire along with our lease instead.
+func (e *Election) closeCampaign() {
+	if e.conf.FastClose {
+		atomic.StoreInt32(&e.isLeader, 0)
+		return
+	}
+
+	if err := e.withDrawCampaign(context.Background()); err != nil {
+		e.onErr(err, "")
+	}
+}
+
 func (e *Election) withDrawCampaign(ctx context.Context) error {
 	defer func() {
 		atomic.StoreInt32(&e.isLeader, 0)
 	}()
 
+	// If withdraw takes longer than our TTL then lease is expired
+	// and we are no longer leader anyway.
+	ctx, cancel := context.WithTimeout(ctx, e.conf.OpTimeout)
+	defer cancel()
+
+	ctx, span := e.tracer.Start(ctx, "etcdutil.Election.withDrawCampaign")
+	defer span.End()
+
 	_, err := e.client.Delete(ctx, e.key)
 	if err != nil {
-		return errors.Wrapf(err, "while withdrawing campaign '%s'", e.key)
+		err = errors.Wrapf(err, "while withdrawing campaign '%s'", e.key)
+		span.RecordError(err)
+		return err
 	}
 	return nil
 }
 
 func (e *Election) registerCampaign(id etcd.LeaseID) (revision int64, err error) {
-	// Create an entry under the election prefix with our lease ID as the key name
-	e.key = fmt.Sprintf("%s%x", e.conf.Election, id)
-	txn := e.client.Txn(e.ctx).If(etcd.Compare(etcd.CreateRevision(e.key), "=", 0))
+	ctx, cancel := context.WithTimeout(e.ctx, e.conf.OpTimeout)
+	defer cancel()
+
+	ctx, span := e.tracer.Start(ctx, "etcdutil.Election.registerCampaign")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+
+	// Create an entry under the election prefix with our priority and lease ID as the key name
+	e.mutex.Lock()
+	e.key = e.campaignKey(id)
+	// Reclaim the campaign of our previous instance if we resumed its lease
+	if e.conf.ResumeKey != "" && id == e.conf.ResumeLease {
+		e.key = e.conf.ResumeKey
+	}
+	e.mutex.Unlock()
+	span.AddEvent("campaign key", map[string]string{"key": e.key})
+	txn := e.client.Txn(ctx).If(etcd.Compare(etcd.CreateRevision(e.key), "=", 0))
 	txn = txn.Then(etcd.OpPut(e.key, e.conf.Candidate, etcd.WithLease(id)))
 	txn = txn.Else(etcd.OpGet(e.key))
 	resp, err := txn.Commit()
@@ -243,32 +680,184 @@ func (e *Election) registerCampaign(id etcd.LeaseID) (revision int64, err error)
 	}
 	revision = resp.Header.Revision
 
-	// This shouldn't happen, our session should always tell us if we disconnected and
-	// etcd should have provided us with a unique lease id. If it does happen then
-	// we should write our candidate name as the value and assume ownership
+	// Unless we resumed the campaign of our previous instance this shouldn't happen, our
+	// session should always tell us if we disconnected and etcd should have provided us
+	// with a unique lease id. If it does happen then we should write our candidate name
+	// as the value and assume ownership, keeping the create revision of the key.
 	if !resp.Succeeded {
 		kv := resp.Responses[0].GetResponseRange().Kvs[0]
 		revision = kv.CreateRevision
+		if e.conf.ResumeKey == "" || id != e.conf.ResumeLease {
+			e.onDuplicateKey(kv)
+		}
 		if string(kv.Value) != e.conf.Candidate {
-			if _, err = e.client.Put(e.ctx, e.key, e.conf.Candidate); err != nil {
+			if _, err = e.client.Put(ctx, e.key, e.conf.Candidate, etcd.WithLease(id)); err != nil {
 				return 0, err
 			}
 		}
 	}
+
+	e.mutex.Lock()
+	e.revision = revision
+	e.mutex.Unlock()
+
+	log.WithFields(logrus.Fields{
+		"election":     e.conf.Election,
+		"campaign_key": e.key,
+		"revision":     revision,
+	}).Debug("campaign registered")
+
+	if e.conf.OnCampaign != nil {
+		e.conf.OnCampaign(e.key, id)
+	}
 	return revision, nil
 }
 
+// Campaign keys with a priority other than 0 are created under this sub-prefix of the
+// election. Keys with the default priority of 0 keep the format of versions before
+// ElectionConfig.Priority, such that those versions agree on the leader while no
+// candidate sets a priority.
+const priorityKeys = "priority/"
+
+// campaignKey returns the key of our campaign attached to the lease
+func (e *Election) campaignKey(id etcd.LeaseID) string {
+	if e.conf.Priority == 0 {
+		return fmt.Sprintf("%s%x", e.conf.Election, id)
+	}
+	return fmt.Sprintf("%s%s%x", e.conf.Election, priorityPrefix(e.conf.Priority), id)
+}
+
+// priorityPrefix returns the fixed width prefix of a campaign key with the given priority,
+// such that sorting campaign keys lexically sorts them by priority.
+func priorityPrefix(priority int) string {
+	return fmt.Sprintf("%s%010d-", priorityKeys, priority)
+}
+
 // getLeader returns a KV pair for the current leader
 func (e *Election) getLeader(ctx context.Context) (*mvccpb.KeyValue, error) {
-	// The leader is the first entry under the election prefix
-	resp, err := e.client.Get(ctx, e.conf.Election, etcd.WithFirstCreate()...)
+	ctx, cancel := context.WithTimeout(ctx, e.conf.OpTimeout)
+	defer cancel()
+
+	if e.conf.LeaderPolicy != nil {
+		return e.policyLeader(ctx)
+	}
+
+	// Candidates with the default priority of 0 are preferred, the leader is the first
+	// of them to join the election
+	resp, err := e.client.Get(ctx, e.conf.Election, etcd.WithRange(e.conf.Election+priorityKeys))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) != 0 {
+		return firstCreated(resp.Kvs), nil
+	}
+	rev := resp.Header.Revision
+
+	// Otherwise find the lowest priority in the election
+	resp, err = e.client.Get(ctx, e.conf.Election+priorityKeys,
+		append(etcd.WithFirstKey(), etcd.WithRev(rev))...)
 	if err != nil {
 		return nil, err
 	}
 	if len(resp.Kvs) == 0 {
 		return nil, nil
 	}
-	return resp.Kvs[0], nil
+
+	// The leader is the first entry created with that priority
+	prefix := e.conf.Election + priorityKeys
+	if key := string(resp.Kvs[0].Key); len(key) >= len(e.conf.Election)+len(priorityPrefix(0)) {
+		prefix = key[:len(e.conf.Election)+len(priorityPrefix(0))]
+	}
+	resp, err = e.client.Get(ctx, prefix, etcd.WithPrefix(), etcd.WithRev(rev))
+	if err != nil {
+		return nil, err
+	}
+	return firstCreated(resp.Kvs), nil
+}
+
+// policyLeader returns a KV pair for the leader selected by ElectionConfig.LeaderPolicy
+func (e *Election) policyLeader(ctx context.Context) (*mvccpb.KeyValue, error) {
+	resp, err := e.client.Get(ctx, e.conf.Election, etcd.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	// Candidates are given in the order they joined the election
+	kvs := resp.Kvs
+	sort.Slice(kvs, func(i, j int) bool {
+		return createdBefore(kvs[i], kvs[j])
+	})
+	candidates := make([]Candidate, len(kvs))
+	for i, kv := range kvs {
+		data, value, _ := e.decodeLeaderData(kv.Value)
+		candidates[i] = Candidate{
+			Key:            string(kv.Key),
+			Data:           data,
+			Value:          value,
+			Priority:       e.parsePriority(string(kv.Key)),
+			CreateRevision: kv.CreateRevision,
+		}
+	}
+
+	if leader := e.conf.LeaderPolicy(candidates); leader != nil {
+		for _, kv := range kvs {
+			if string(kv.Key) == leader.Key {
+				return kv, nil
+			}
+		}
+	}
+	return e.defaultLeader(kvs), nil
+}
+
+// parsePriority returns the priority encoded in the campaign key
+func (e *Election) parsePriority(key string) int {
+	start := len(e.conf.Election) + len(priorityKeys)
+	end := len(e.conf.Election) + len(priorityPrefix(0)) - 1
+	if !strings.HasPrefix(key, e.conf.Election+priorityKeys) || len(key) < end {
+		return 0
+	}
+	priority, _ := strconv.Atoi(key[start:end])
+	return priority
+}
+
+// defaultLeader returns the first created of the keys with the lowest priority
+func (e *Election) defaultLeader(kvs []*mvccpb.KeyValue) *mvccpb.KeyValue {
+	var lowest []*mvccpb.KeyValue
+	var lowestPriority int
+	for _, kv := range kvs {
+		priority := e.parsePriority(string(kv.Key))
+		switch {
+		case lowest == nil || priority < lowestPriority:
+			lowest, lowestPriority = []*mvccpb.KeyValue{kv}, priority
+		case priority == lowestPriority:
+			lowest = append(lowest, kv)
+		}
+	}
+	return firstCreated(lowest)
+}
+
+// firstCreated returns the key with the lowest create revision, or nil if there are none.
+// Keys written in the same transaction share a create revision, such ties are broken by
+// key, such that every candidate agrees on the same leader. The mod revision is not
+// considered as it changes with every heartbeat of the leader.
+func firstCreated(kvs []*mvccpb.KeyValue) *mvccpb.KeyValue {
+	var first *mvccpb.KeyValue
+	for _, kv := range kvs {
+		if first == nil || createdBefore(kv, first) {
+			first = kv
+		}
+	}
+	return first
+}
+
+func createdBefore(a, b *mvccpb.KeyValue) bool {
+	if a.CreateRevision != b.CreateRevision {
+		return a.CreateRevision < b.CreateRevision
+	}
+	return bytes.Compare(a.Key, b.Key) < 0
 }
 
 // watchCampaign monitors the status of the campaign and notifying any
@@ -277,21 +866,48 @@ func (e *Election) watchCampaign(rev int64) error {
 	var watchChan etcd.WatchChan
 	ready := make(chan struct{})
 
+	// Watch through the client, such that a namespaced client watches within its
+	// namespace, and cancel the context to close the watch when it is over.
+	watchCtx, cancelWatch := context.WithCancel(e.ctx)
+
+	// Ticks while the watch is running, heartbeats are only written while we are leader
+	var heartbeat <-chan time.Time
+	stopHeartbeat := func() {}
+	if e.conf.HeartbeatInterval != 0 {
+		ticker := e.conf.Clock.NewTicker(e.conf.HeartbeatInterval)
+		heartbeat, stopHeartbeat = ticker.C(), ticker.Stop
+	}
+
+	// The span ends when the watch is over, not when this function returns
+	_, span := e.tracer.Start(e.ctx, "etcdutil.Election.watchCampaign")
+	fail := func(err error) error {
+		cancelWatch()
+		stopHeartbeat()
+		span.RecordError(err)
+		span.End()
+		return err
+	}
+	onLeaderChange := func(kv *mvccpb.KeyValue) {
+		e.onLeaderChange(kv)
+		span.AddEvent("leader change", map[string]string{
+			"leader_key": string(kv.Key),
+			"is_leader":  fmt.Sprintf("%t", e.IsLeader()),
+		})
+	}
+
 	// Get the current leader of this election
 	leaderKV, err := e.getLeader(e.ctx)
 	if err != nil {
-		return errors.Wrap(err, "while querying for current leader")
+		return fail(errors.Wrap(err, "while querying for current leader"))
 	}
 	if leaderKV == nil {
-		return errors.Wrap(err, "found no leader when watch began")
+		return fail(errors.Wrap(err, "found no leader when watch began"))
 	}
 
-	watcher := etcd.NewWatcher(e.client)
-
 	// We do this because watcher does not reliably return when errors occur on connect
 	// or when cancelled (See https://github.com/etcd-io/etcd/pull/10020)
 	go func() {
-		watchChan = watcher.Watch(etcd.WithRequireLeader(e.ctx), e.conf.Election,
+		watchChan = e.client.Watch(etcd.WithRequireLeader(watchCtx), e.conf.Election,
 			etcd.WithRev(int64(rev+1)), etcd.WithPrefix())
 		close(ready)
 	}()
@@ -299,61 +915,83 @@ func (e *Election) watchCampaign(rev int64) error {
 	select {
 	case <-ready:
 	case <-e.ctx.Done():
-		return errors.Wrap(e.ctx.Err(), "while waiting for etcd watch to start")
+		return fail(errors.Wrap(e.ctx.Err(), "while waiting for etcd watch to start"))
+	}
+	if e.conf.OnWatchStart != nil {
+		e.conf.OnWatchStart(rev)
 	}
 
 	// Notify the observers of the current leader
-	e.onLeaderChange(leaderKV)
+	onLeaderChange(leaderKV)
 
 	e.wg.Until(func(done chan struct{}) bool {
 		select {
 		case resp := <-watchChan:
 			if resp.Canceled {
-				e.onFatalErr(errors.New("remote server cancelled watch"), "during campaign watch")
+				err := errors.New("remote server cancelled watch")
+				e.onFatalErr(err, "during campaign watch")
+				fail(err)
 				return false
 			}
 			if err := resp.Err(); err != nil {
 				e.onFatalErr(err, "during campaign watch, remote server returned err")
+				fail(err)
 				return false
 			}
 
 			// Watch for changes in leadership
 			for _, event := range resp.Events {
+				// The value of the current leader changed, which doesn't change leadership
+				if event.Type == etcd.EventTypePut && bytes.Equal(event.Kv.Key, leaderKV.Key) {
+					e.setLeaderData(event.Kv.Value)
+					// A LeaderPolicy may select the leader by the value of the candidates
+					if e.conf.LeaderPolicy == nil {
+						continue
+					}
+				}
 				if event.Type == etcd.EventTypeDelete || event.Type == etcd.EventTypePut {
-					// If the key is for our current leader
-					if bytes.Compare(event.Kv.Key, leaderKV.Key) == 0 {
+					// If the key is for our current leader, or a new candidate
+					// which might have a lower priority than our current leader,
+					// or any change if a LeaderPolicy selects the leader
+					if bytes.Compare(event.Kv.Key, leaderKV.Key) == 0 || event.Type == etcd.EventTypePut ||
+						e.conf.LeaderPolicy != nil {
 						// Check our leadership status
 						resp, err := e.getLeader(e.ctx)
 						if err != nil {
 							e.onFatalErr(err, "while querying for new leader")
+							fail(err)
 							return false
 						}
 
 						// If we have no leader
 						if resp == nil {
 							e.onFatalErr(err, "After etcd event no leader was found, restarting election")
+							fail(errors.New("no leader found after etcd event"))
 							return false
 						}
 						// Notify if leadership has changed
 						if bytes.Compare(resp.Key, leaderKV.Key) != 0 {
 							leaderKV = resp
-							e.onLeaderChange(leaderKV)
+							onLeaderChange(leaderKV)
 						}
 					}
 				}
 			}
+		case <-heartbeat:
+			if e.IsLeader() {
+				// Our campaign key is still held with our lease, so report the
+				// error without giving up leadership
+				if err := e.putHeartbeat(); err != nil {
+					e.notify(Event{Err: err})
+				}
+			}
 		case <-done:
-			watcher.Close()
-			// If withdraw takes longer than our TTL then lease is expired
-			// and we are no longer leader anyway.
-			ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
-
+			cancelWatch()
+			stopHeartbeat()
 			// Withdraw our candidacy because of shutdown
-			if err := e.withDrawCampaign(ctx); err != nil {
-				e.onErr(err, "")
-			}
-			e.onLeaderChange(&mvccpb.KeyValue{})
-			cancel()
+			e.closeCampaign()
+			onLeaderChange(&mvccpb.KeyValue{})
+			span.End()
 			return false
 		}
 		return true
@@ -363,8 +1001,10 @@ func (e *Election) watchCampaign(rev int64) error {
 
 func (e *Election) onLeaderChange(kv *mvccpb.KeyValue) {
 	event := Event{}
+	var leaderRevision int64
 
 	if kv != nil {
+		leaderRevision = kv.CreateRevision
 		if string(kv.Key) == e.key {
 			atomic.StoreInt32(&e.isLeader, 1)
 			event.IsLeader = true
@@ -372,14 +1012,115 @@ func (e *Election) onLeaderChange(kv *mvccpb.KeyValue) {
 			atomic.StoreInt32(&e.isLeader, 0)
 		}
 		event.LeaderKey = string(kv.Key)
-		event.LeaderData = string(kv.Value)
+		event.LeaderData, event.LeaderValue, event.Err = e.decodeLeaderData(kv.Value)
 	} else {
 		event.IsDone = true
 	}
 
-	for _, v := range e.observers {
-		v(event)
+	e.mutex.Lock()
+	e.leaderKey = event.LeaderKey
+	e.leaderData = event.LeaderData
+	e.leaderValue = event.LeaderValue
+	e.leaderHeartbeat = parseHeartbeat(kv)
+	if event.IsLeader && e.firstLeaderAt.IsZero() {
+		e.firstLeaderAt = e.conf.Clock.Now()
 	}
+	revision := e.revision
+	e.mutex.Unlock()
+
+	log.WithFields(logrus.Fields{
+		"election":        e.conf.Election,
+		"campaign_key":    e.key,
+		"revision":        revision,
+		"leader_key":      event.LeaderKey,
+		"leader_revision": leaderRevision,
+		"is_leader":       event.IsLeader,
+		"is_done":         event.IsDone,
+	}).Info("leader changed")
+
+	if event.IsLeader && e.conf.OnFirstLeader != nil {
+		e.firstLeaderOnce.Do(e.conf.OnFirstLeader)
+	}
+	e.notify(event)
+}
+
+// decodeLeaderData returns the candidate data of the campaign value without any heartbeat,
+// and the data decoded by the Codec if provided
+func (e *Election) decodeLeaderData(value []byte) (string, interface{}, error) {
+	data, _ := splitHeartbeat(string(value))
+	if e.conf.Codec == nil || len(data) == 0 {
+		return data, nil, nil
+	}
+	v, err := e.conf.Codec.Unmarshal([]byte(data))
+	if err != nil {
+		return data, nil, errors.Wrap(err, "while decoding leader data")
+	}
+	return data, v, nil
+}
+
+// parseHeartbeat returns the heartbeat in the campaign value, or the zero time if there is none
+func parseHeartbeat(kv *mvccpb.KeyValue) time.Time {
+	if kv == nil {
+		return time.Time{}
+	}
+	_, heartbeat := splitHeartbeat(string(kv.Value))
+	return heartbeat
+}
+
+// splitHeartbeat splits a campaign value into the candidate data and the heartbeat appended
+// to it. The value is returned whole with the zero time unless it ends with a heartbeat,
+// such that candidate data containing the separator is left intact.
+func splitHeartbeat(value string) (string, time.Time) {
+	i := strings.LastIndex(value, heartbeatSeparator)
+	if i == -1 {
+		return value, time.Time{}
+	}
+	nanos, err := strconv.ParseInt(value[i+len(heartbeatSeparator):], 10, 64)
+	if err != nil {
+		return value, time.Time{}
+	}
+	return value[:i], time.Unix(0, nanos)
+}
+
+// setLeaderData updates the data and heartbeat of the current leader after its value changed
+func (e *Election) setLeaderData(value []byte) {
+	data, v, _ := e.decodeLeaderData(value)
+	heartbeat := parseHeartbeat(&mvccpb.KeyValue{Value: value})
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.leaderData = data
+	e.leaderValue = v
+	e.leaderHeartbeat = heartbeat
+}
+
+// putHeartbeat rewrites our campaign value with the current time, keeping our lease
+func (e *Election) putHeartbeat() error {
+	ctx, cancel := context.WithTimeout(e.ctx, e.conf.OpTimeout)
+	defer cancel()
+
+	e.mutex.Lock()
+	key := e.key
+	e.mutex.Unlock()
+
+	value := e.conf.Candidate + heartbeatSeparator + strconv.FormatInt(e.conf.Clock.Now().UnixNano(), 10)
+	if _, err := e.client.Put(ctx, key, value, etcd.WithIgnoreLease()); err != nil {
+		return errors.Wrapf(err, "while writing heartbeat to '%s'", key)
+	}
+	return nil
+}
+
+// onDuplicateKey reports that our campaign key already existed when registering our campaign
+func (e *Election) onDuplicateKey(kv *mvccpb.KeyValue) {
+	atomic.AddInt64(&e.duplicateKeys, 1)
+	warn := fmt.Sprintf("campaign key '%s' already exists with lease '%x', assuming ownership",
+		kv.Key, kv.Lease)
+	log.WithFields(logrus.Fields{
+		"election":     e.conf.Election,
+		"campaign_key": string(kv.Key),
+		"revision":     kv.CreateRevision,
+	}).Warn(warn)
+	e.notify(Event{Warn: warn})
 }
 
 // onErr reports errors the the observer
@@ -390,14 +1131,103 @@ func (e *Election) onErr(err error, msg string) {
 		err = errors.Wrap(err, msg)
 	}
 
+	e.notify(Event{Err: err})
+}
+
+// notify delivers the event to a copy of the current observers such that
+// observers may add or remove observers while handling the event
+func (e *Election) notify(event Event) {
+	e.notifyMutex.Lock()
+	defer e.notifyMutex.Unlock()
+
+	e.observersMutex.Lock()
+	observers := make([]EventObserver, 0, len(e.observers))
 	for _, v := range e.observers {
-		v(Event{Err: err})
+		observers = append(observers, v)
 	}
+	e.observersMutex.Unlock()
+
+	for _, v := range observers {
+		v(event)
+	}
+}
+
+// AddObserver registers an observer which receives all future election events.
+// Adding an observer with an `id` already in use replaces the previous observer.
+func (e *Election) AddObserver(id string, o EventObserver) {
+	e.observersMutex.Lock()
+	defer e.observersMutex.Unlock()
+	e.observers[id] = o
+}
+
+// AddObserverWithReplay is like AddObserver but first calls the observer with an
+// event describing the current leader, so an observer added after the election
+// started does not have to wait for the next change in leadership. No events are
+// delivered to the observer until the replay returns, so the replayed event is always
+// the first event the observer receives. The replay is delivered on the calling routine,
+// so it is safe to call from within an observer.
+func (e *Election) AddObserverWithReplay(id string, o EventObserver) {
+	// Events delivered while the replay is running wait for it to return
+	var replay sync.Mutex
+	replay.Lock()
+	defer replay.Unlock()
+
+	// Register before reading the current leader, such that a change of leadership
+	// in between is delivered after the replay rather than missed.
+	e.AddObserver(id, func(event Event) {
+		replay.Lock()
+		defer replay.Unlock()
+		o(event)
+	})
+
+	e.mutex.Lock()
+	event := Event{
+		IsLeader:    e.IsLeader(),
+		LeaderKey:   e.leaderKey,
+		LeaderData:  e.leaderData,
+		LeaderValue: e.leaderValue,
+	}
+	e.mutex.Unlock()
+
+	o(event)
+}
+
+// WaitForLeader blocks until the data of the leader equals `candidate` or the context is
+// cancelled. It returns immediately if `candidate` is already leader, and returns an error
+// if the election is closed while waiting.
+func (e *Election) WaitForLeader(ctx context.Context, candidate string) error {
+	id := fmt.Sprintf("wait-for-leader-%d", atomic.AddInt64(&e.waiters, 1))
+	var once sync.Once
+	elected := make(chan struct{})
+
+	e.AddObserverWithReplay(id, func(event Event) {
+		if event.LeaderKey != "" && event.LeaderData == candidate {
+			once.Do(func() { close(elected) })
+		}
+	})
+	defer e.RemoveObserver(id)
+
+	select {
+	case <-elected:
+		return nil
+	case <-e.done:
+		return errors.New("election closed while waiting for leader")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RemoveObserver removes the observer registered with `id`
+func (e *Election) RemoveObserver(id string) {
+	e.observersMutex.Lock()
+	defer e.observersMutex.Unlock()
+	delete(e.observers, id)
 }
 
 // onFatalErr reports errors to the observer and resets the election and session
 func (e *Election) onFatalErr(err error, msg string) {
 	e.onErr(err, msg)
+	atomic.AddInt64(&e.sessionResets, 1)
 	// We call this in a go routine to avoid blocking on `Stop()` calls
 	go e.session.Reset()
 }
@@ -408,6 +1238,163 @@ func (e *Election) Close() {
 	e.wg.Wait()
 	// Emit the `Done:true` event
 	e.onLeaderChange(nil)
+	e.closeOnce.Do(func() { close(e.done) })
+}
+
+// Done returns a channel that is closed once the election has shut down after
+// Close() is called. Like context.Context.Done() it is safe to select on from
+// many goroutines.
+func (e *Election) Done() <-chan struct{} {
+	return e.done
+}
+
+// Stats returns a snapshot of the current state of the election
+func (e *Election) Stats() ElectionStats {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return ElectionStats{
+		IsLeader:              e.IsLeader(),
+		IsRunning:             e.isRunning,
+		CurrentLeaderKey:      e.leaderKey,
+		CurrentLeaderData:     e.leaderData,
+		CampaignKey:           e.key,
+		SessionLeaseID:        e.leaseID,
+		BackoffAttempts:       e.backOff.Attempts(),
+		SessionResets:         e.SessionResets(),
+		DuplicateCampaignKeys: atomic.LoadInt64(&e.duplicateKeys),
+	}
+}
+
+// SessionResets returns the number of times the session was reset after a fatal error
+// during the campaign. A steadily increasing count indicates etcd is unstable.
+func (e *Election) SessionResets() int64 {
+	return atomic.LoadInt64(&e.sessionResets)
+}
+
+// LeaderHeartbeat returns the time of the last heartbeat written by the leader, or the
+// zero time if the leader does not write heartbeats. A heartbeat older than a few times
+// the ElectionConfig.HeartbeatInterval of the leader indicates it has stopped functioning.
+func (e *Election) LeaderHeartbeat() time.Time {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.leaderHeartbeat
+}
+
+// RunAsLeader calls `fn` whenever our candidate is leader, with a context which is cancelled
+// the moment leadership is lost, and calls it again once leadership is regained. It blocks
+// until `fn` returns while leader, in which case the result of `fn` is returned, or until
+// `ctx` is done or the election is closed. The result of `fn` after its context was
+// cancelled because leadership was lost is ignored.
+//
+//	err := election.RunAsLeader(ctx, func(ctx context.Context) error {
+//		for {
+//			select {
+//			case <-time.After(time.Second):
+//				// Do work only the leader should do
+//			case <-ctx.Done():
+//				return ctx.Err()
+//			}
+//		}
+//	})
+func (e *Election) RunAsLeader(ctx context.Context, fn func(ctx context.Context) error) error {
+	id := fmt.Sprintf("run-as-leader-%d", atomic.AddInt64(&e.waiters, 1))
+
+	// Holds the latest leadership status, observers are serialized so never block
+	changes := make(chan bool, 1)
+	e.AddObserverWithReplay(id, func(event Event) {
+		if event.Warn != "" {
+			return
+		}
+		select {
+		case <-changes:
+		default:
+		}
+		changes <- event.IsLeader && !event.IsDone
+	})
+	defer e.RemoveObserver(id)
+
+	var isLeader bool
+	for {
+		select {
+		case isLeader = <-changes:
+		case <-e.done:
+			return errors.New("election closed while waiting for leadership")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if !isLeader {
+			continue
+		}
+
+		fnCtx, cancel := context.WithCancel(ctx)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- fn(fnCtx)
+		}()
+
+		for isLeader {
+			select {
+			case isLeader = <-changes:
+			case err := <-errs:
+				cancel()
+				return err
+			case <-e.done:
+				cancel()
+				<-errs
+				return errors.New("election closed while running as leader")
+			case <-ctx.Done():
+				cancel()
+				<-errs
+				return ctx.Err()
+			}
+		}
+
+		// Leadership was lost
+		cancel()
+		<-errs
+	}
+}
+
+// TimeToLeadership returns the time from the creation of the election until our candidate
+// first became leader, as measured by ElectionConfig.Clock, and false if our candidate has
+// never been leader.
+func (e *Election) TimeToLeadership() (time.Duration, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.firstLeaderAt.IsZero() {
+		return 0, false
+	}
+	return e.firstLeaderAt.Sub(e.createdAt), true
+}
+
+// String summarizes the state of the election on a single line for logging
+func (e *Election) String() string {
+	stats := e.Stats()
+	return fmt.Sprintf("Election{name: %s, candidate: %s, isLeader: %t, isRunning: %t, "+
+		"leaderKey: %s, leaderData: %s, leaseID: %x}", e.conf.Election, e.conf.Candidate,
+		stats.IsLeader, stats.IsRunning, stats.CurrentLeaderKey, stats.CurrentLeaderData,
+		int64(stats.SessionLeaseID))
+}
+
+// Leader returns the candidate data of the last observed leader, and true if that
+// leader is our candidate. The candidate is empty if no leader has been observed.
+func (e *Election) Leader() (candidate string, isSelf bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.leaderData, e.leaderKey != "" && e.leaderKey == e.key
+}
+
+func (e *Election) running() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.isRunning
+}
+
+func (e *Election) setRunning(running bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.isRunning = running
 }
 
 // IsLeader returns true if we are leader
@@ -417,13 +1404,17 @@ func (e *Election) IsLeader() bool {
 
 // Concede concedes leadership if we are leader and restarts the campaign returns true.
 // if we are not leader do nothing and return false. If you want to concede leadership
-// and cancel the campaign call Close() instead.
+// and cancel the campaign call Close() instead. See ElectionConfig.ConcedeCooldown to
+// prevent us from immediately becoming leader again.
 func (e *Election) Concede() (bool, error) {
 	isLeader := atomic.LoadInt32(&e.isLeader)
 	if isLeader == 0 {
 		return false, nil
 	}
+	e.mutex.Lock()
 	oldCampaignKey := e.key
+	e.cooldownUntil = e.conf.Clock.Now().Add(e.conf.ConcedeCooldown)
+	e.mutex.Unlock()
 	e.session.Reset()
 
 	// Ensure there are no lingering candiates
@@ -443,3 +1434,78 @@ type AlwaysLeaderMock struct{}
 func (s *AlwaysLeaderMock) IsLeader() bool         { return true }
 func (s *AlwaysLeaderMock) Concede() (bool, error) { return true, nil }
 func (s *AlwaysLeaderMock) Close()                 {}
+
+// ManualElector is a LeaderElector for tests whose leadership is flipped by calling
+// SetLeader(), such that failover logic can be driven deterministically without etcd.
+type ManualElector struct {
+	// Serializes the delivery of events to the observer
+	notifyMutex sync.Mutex
+	mutex       sync.Mutex
+	observer    EventObserver
+	isLeader    bool
+	isDone      bool
+}
+
+var _ LeaderElector = &ManualElector{}
+
+// NewManualElector returns a ManualElector which is not leader. The optional observer
+// is called with an Event every time leadership changes, like ElectionConfig.EventObserver.
+func NewManualElector(observer EventObserver) *ManualElector {
+	return &ManualElector{observer: observer}
+}
+
+// SetLeader sets our leadership and notifies the observer if it changed
+func (m *ManualElector) SetLeader(isLeader bool) {
+	m.update(func() bool {
+		if m.isDone || m.isLeader == isLeader {
+			return false
+		}
+		m.isLeader = isLeader
+		return true
+	})
+}
+
+func (m *ManualElector) IsLeader() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.isLeader
+}
+
+// Concede gives up leadership if we are leader and returns true
+func (m *ManualElector) Concede() (bool, error) {
+	var conceded bool
+	m.update(func() bool {
+		conceded = m.isLeader
+		m.isLeader = false
+		return conceded
+	})
+	return conceded, nil
+}
+
+// Close gives up leadership and notifies the observer the election is done
+func (m *ManualElector) Close() {
+	m.update(func() bool {
+		if m.isDone {
+			return false
+		}
+		m.isLeader = false
+		m.isDone = true
+		return true
+	})
+}
+
+// update applies `change` and notifies the observer if it returns true. The observer is
+// called without the state locked, so it may call IsLeader().
+func (m *ManualElector) update(change func() bool) {
+	m.notifyMutex.Lock()
+	defer m.notifyMutex.Unlock()
+
+	m.mutex.Lock()
+	changed := change()
+	event := Event{IsLeader: m.isLeader, IsDone: m.isDone}
+	m.mutex.Unlock()
+
+	if changed && m.observer != nil {
+		m.observer(event)
+	}
+}
@@ -2,27 +2,44 @@ package etcdutil_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/mailgun/holster/clock"
 	"github.com/mailgun/holster/etcdutil"
+	"github.com/mailgun/holster/etcdutil/etcdtest"
 	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 )
 
 func TestElection(t *testing.T) {
+	client, teardown := etcdtest.StartEmbedded(t)
+	defer teardown()
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 
 	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/my-election",
+			Candidate: "me",
+		},
 		EventObserver: func(e etcdutil.Event) {
 			if e.Err != nil {
 				t.Fatal(e.Err.Error())
 			}
 		},
-		Election:  "/my-election",
-		Candidate: "me",
 	})
 	require.Nil(t, err)
 
@@ -31,6 +48,346 @@ func TestElection(t *testing.T) {
 	assert.Equal(t, false, election.IsLeader())
 }
 
+func TestElectionStats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/my-election",
+			Candidate: "me",
+		},
+	})
+	require.Nil(t, err)
+
+	stats := election.Stats()
+	assert.True(t, stats.IsLeader)
+	assert.True(t, stats.IsRunning)
+	assert.NotEqual(t, "", stats.CampaignKey)
+	assert.Equal(t, stats.CampaignKey, stats.CurrentLeaderKey)
+	assert.Equal(t, "me", stats.CurrentLeaderData)
+	assert.NotEqual(t, etcdutil.NoLease, stats.SessionLeaseID)
+	assert.Equal(t, 0, stats.BackoffAttempts)
+
+	election.Close()
+	stats = election.Stats()
+	assert.False(t, stats.IsLeader)
+	assert.Equal(t, "", stats.CurrentLeaderKey)
+}
+
+func TestElectionDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/my-election",
+			Candidate: "me",
+		},
+	})
+	require.Nil(t, err)
+
+	select {
+	case <-election.Done():
+		t.Fatal("done before Close()")
+	default:
+	}
+
+	election.Close()
+
+	select {
+	case <-election.Done():
+	default:
+		t.Fatal("not done after Close()")
+	}
+
+	// Closing again must not close the channel twice
+	election.Close()
+	<-election.Done()
+}
+
+func TestElectionAddObserverWithReplay(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/my-election",
+			Candidate: "me",
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	events := make(chan etcdutil.Event, 10)
+	election.AddObserverWithReplay("mid-run", func(e etcdutil.Event) {
+		events <- e
+	})
+
+	// The current leadership is delivered before AddObserverWithReplay() returns
+	select {
+	case e := <-events:
+		assert.True(t, e.IsLeader)
+		assert.False(t, e.IsDone)
+		assert.Equal(t, "me", e.LeaderData)
+		assert.Equal(t, election.Stats().CampaignKey, e.LeaderKey)
+	default:
+		t.Fatal("observer did not receive the current leader")
+	}
+}
+
+func TestElectionObserverAddsObserver(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/observer-adds-observer-election",
+			Candidate: "me",
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	// Calls made from within an observer must not deadlock event delivery
+	errs := make(chan error, 10)
+	replays := make(chan etcdutil.Event, 10)
+	election.AddObserver("outer", func(e etcdutil.Event) {
+		if !e.IsLeader {
+			return
+		}
+		election.AddObserverWithReplay("inner", func(e etcdutil.Event) {
+			replays <- e
+		})
+		errs <- election.WaitForLeader(ctx, "me")
+	})
+
+	conceded, err := election.Concede()
+	require.Nil(t, err)
+	assert.True(t, conceded)
+
+	select {
+	case err := <-errs:
+		assert.Nil(t, err)
+	case <-ctx.Done():
+		require.FailNow(t, "observer deadlocked")
+	}
+	select {
+	case e := <-replays:
+		assert.Equal(t, "me", e.LeaderData)
+	case <-ctx.Done():
+		require.FailNow(t, "inner observer did not receive the current leader")
+	}
+}
+
+func TestElectionLogsRevisions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	hook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/my-election",
+			Candidate: "me",
+		},
+	})
+	require.Nil(t, err)
+	election.Close()
+
+	var changes int
+	for _, entry := range hook.AllEntries() {
+		if entry.Message != "leader changed" {
+			continue
+		}
+		changes++
+		assert.Contains(t, entry.Data, "revision")
+		assert.Contains(t, entry.Data, "leader_revision")
+	}
+	assert.NotZero(t, changes)
+}
+
+func TestElectionLeader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	c1, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/leader-election",
+			Candidate: "c1",
+		},
+	})
+	require.Nil(t, err)
+
+	c2Chan := make(chan etcdutil.Event, 5)
+	c2, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/leader-election",
+			Candidate: "c2",
+		},
+		EventObserver: func(e etcdutil.Event) {
+			c2Chan <- e
+		},
+	})
+	require.Nil(t, err)
+	defer c2.Close()
+	<-c2Chan
+
+	candidate, isSelf := c1.Leader()
+	assert.Equal(t, "c1", candidate)
+	assert.True(t, isSelf)
+
+	candidate, isSelf = c2.Leader()
+	assert.Equal(t, "c1", candidate)
+	assert.False(t, isSelf)
+
+	// c2 becomes leader once c1 leaves
+	c1.Close()
+	for e := range c2Chan {
+		if e.IsLeader {
+			break
+		}
+	}
+
+	candidate, isSelf = c2.Leader()
+	assert.Equal(t, "c2", candidate)
+	assert.True(t, isSelf)
+}
+
+func TestElectionConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		conf etcdutil.ElectionConfig
+		err  string
+	}{{
+		name: "valid",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Election: "my-election", Candidate: "me", TTL: 5}},
+	}, {
+		name: "defaults are valid",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Election: "my-election"}},
+	}, {
+		name: "empty election",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Candidate: "me"}},
+		err:  "ElectionConfig.Election can not be empty",
+	}, {
+		name: "election escapes prefix",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Election: "../my-election"}},
+		err:  "ElectionConfig.Election '../my-election' can not contain '..'",
+	}, {
+		name: "negative ttl",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Election: "my-election", TTL: -1}},
+		err:  "ElectionConfig.TTL '-1' can not be negative",
+	}, {
+		name: "negative priority",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Election: "my-election", Priority: -1}},
+		err:  "ElectionConfig.Priority '-1' can not be negative",
+	}, {
+		name: "candidate value without codec",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Election: "my-election"}, CandidateValue: "me"},
+		err:  "ElectionConfig.CandidateValue requires a Codec",
+	}, {
+		name: "hostname unavailable",
+		conf: etcdutil.ElectionConfig{
+			ElectionSettings: etcdutil.ElectionSettings{
+				Election: "my-election",
+			},
+			HostnameFunc: func() (string, error) { return "", errors.New("no hostname") },
+		},
+		err: "ElectionConfig.Candidate is empty and hostname is unavailable: no hostname",
+	}, {
+		name: "negative op timeout",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Election: "my-election", OpTimeout: -time.Second}},
+		err:  "ElectionConfig.OpTimeout '-1s' can not be negative",
+	}, {
+		name: "negative heartbeat interval",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Election: "my-election", HeartbeatInterval: -time.Second}},
+		err:  "ElectionConfig.HeartbeatInterval '-1s' can not be negative",
+	}, {
+		name: "candidate contains heartbeat separator",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Election: "my-election", Candidate: "me\x00heartbeat=1"}},
+		err:  `ElectionConfig.Candidate can not contain "\x00heartbeat="`,
+	}, {
+		name: "resume key without lease",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Election: "my-election", ResumeKey: "/elections/my-election1"}},
+		err:  "ElectionConfig.ResumeKey requires a ResumeLease",
+	}, {
+		name: "negative concede cooldown",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Election: "my-election", ConcedeCooldown: -time.Second}},
+		err:  "ElectionConfig.ConcedeCooldown '-1s' can not be negative",
+	}, {
+		name: "negative initial timeout",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{Election: "my-election", InitialTimeout: -time.Second}},
+		err:  "ElectionConfig.InitialTimeout '-1s' can not be negative",
+	}, {
+		name: "all errors are joined",
+		conf: etcdutil.ElectionConfig{ElectionSettings: etcdutil.ElectionSettings{TTL: -1}},
+		err:  "ElectionConfig.Election can not be empty; ElectionConfig.TTL '-1' can not be negative",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.conf.Validate()
+			if tc.err == "" {
+				assert.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			assert.Equal(t, tc.err, err.Error())
+		})
+	}
+}
+
+func TestNewElectionInvalidConfig(t *testing.T) {
+	_, err := etcdutil.NewElection(context.Background(), client, etcdutil.ElectionConfig{})
+	require.NotNil(t, err)
+	assert.Equal(t, "ElectionConfig.Election can not be empty", err.Error())
+}
+
+func TestLoadElectionConfig(t *testing.T) {
+	conf, err := etcdutil.LoadElectionConfig(strings.NewReader(`{
+		"election": "load-config-election",
+		"candidate": "me",
+		"ttl": 5,
+		"op_timeout": "2s",
+		"heartbeat_interval": "500ms"
+	}`))
+	require.Nil(t, err)
+	assert.Equal(t, "load-config-election", conf.Election)
+	assert.Equal(t, "me", conf.Candidate)
+	assert.Equal(t, int64(5), conf.TTL)
+	assert.Equal(t, 2*time.Second, conf.OpTimeout)
+	assert.Equal(t, 500*time.Millisecond, conf.HeartbeatInterval)
+
+	// Should round trip
+	b, err := json.Marshal(conf)
+	require.Nil(t, err)
+	assert.Contains(t, string(b), `"op_timeout":"2s"`)
+	var decoded etcdutil.ElectionConfig
+	require.Nil(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, conf, decoded)
+
+	_, err = etcdutil.LoadElectionConfig(strings.NewReader(`{"election": "my-election", "op_timeout": "soon"}`))
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "op_timeout='soon' is not a duration")
+
+	_, err = etcdutil.LoadElectionConfig(strings.NewReader(`{"candidate": "me"}`))
+	require.NotNil(t, err)
+	assert.Equal(t, "ElectionConfig.Election can not be empty", err.Error())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	// Callbacks are set in code
+	conf.EventObserver = func(e etcdutil.Event) {
+		if e.Err != nil {
+			t.Fatal(e.Err.Error())
+		}
+	}
+	election, err := etcdutil.NewElection(ctx, client, conf)
+	require.Nil(t, err)
+	defer election.Close()
+	assert.Equal(t, true, election.IsLeader())
+}
+
 func TestTwoCampaigns(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
@@ -38,26 +395,30 @@ func TestTwoCampaigns(t *testing.T) {
 	logrus.SetLevel(logrus.DebugLevel)
 
 	c1, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/my-election",
+			Candidate: "c1",
+		},
 		EventObserver: func(e etcdutil.Event) {
 			if e.Err != nil {
 				t.Fatal(e.Err.Error())
 			}
 		},
-		Election:  "/my-election",
-		Candidate: "c1",
 	})
 	require.Nil(t, err)
 
 	c2Chan := make(chan etcdutil.Event, 5)
 	c2, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/my-election",
+			Candidate: "c2",
+		},
 		EventObserver: func(e etcdutil.Event) {
 			if err != nil {
 				t.Fatal(err.Error())
 			}
 			c2Chan <- e
 		},
-		Election:  "/my-election",
-		Candidate: "c2",
 	})
 	require.Nil(t, err)
 
@@ -84,3 +445,1095 @@ func TestTwoCampaigns(t *testing.T) {
 	assert.Equal(t, false, e.IsLeader)
 	assert.Equal(t, true, e.IsDone)
 }
+
+func TestElectionConcedeCooldown(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	clk := clock.NewFrozenClock(time.Now())
+	first, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:        "/cooldown-election",
+			Candidate:       "first",
+			ConcedeCooldown: time.Second * 2,
+		},
+		Clock: clk,
+	})
+	require.Nil(t, err)
+	defer first.Close()
+	assert.Equal(t, true, first.IsLeader())
+
+	second, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/cooldown-election",
+			Candidate: "second",
+		},
+	})
+	require.Nil(t, err)
+	defer second.Close()
+	assert.Equal(t, false, second.IsLeader())
+
+	secondChan := make(chan etcdutil.Event, 5)
+	second.AddObserver("test", func(e etcdutil.Event) {
+		secondChan <- e
+	})
+
+	conceded, err := first.Concede()
+	require.Nil(t, err)
+	assert.True(t, conceded)
+
+	for {
+		select {
+		case e := <-secondChan:
+			if !e.IsLeader {
+				continue
+			}
+		case <-ctx.Done():
+			require.FailNow(t, "second candidate did not become leader")
+		}
+		break
+	}
+	assert.Equal(t, true, second.IsLeader())
+	assert.Equal(t, false, first.IsLeader())
+
+	// The first candidate does not campaign again until the cooldown is over
+	candidates := func() int64 {
+		resp, err := client.Get(ctx, "/elections/cooldown-election", etcd.WithPrefix(), etcd.WithCountOnly())
+		require.Nil(t, err)
+		return resp.Count
+	}
+	require.True(t, clk.Wait4Scheduled(1, time.Second))
+	clk.Advance(time.Second)
+	time.Sleep(time.Millisecond * 500)
+	assert.Equal(t, int64(1), candidates())
+
+	clk.Advance(time.Second)
+	for candidates() != 2 {
+		select {
+		case <-time.After(time.Millisecond * 50):
+		case <-ctx.Done():
+			require.FailNow(t, "first candidate did not campaign after the cooldown")
+		}
+	}
+	assert.Equal(t, true, second.IsLeader())
+	assert.Equal(t, false, first.IsLeader())
+}
+
+func TestElectionOnFirstLeader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var calls int32
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/first-leader-election",
+			Candidate: "me",
+		},
+		OnFirstLeader: func() {
+			atomic.AddInt32(&calls, 1)
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+	assert.Equal(t, true, election.IsLeader())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	events := make(chan etcdutil.Event, 5)
+	election.AddObserver("test", func(e etcdutil.Event) {
+		events <- e
+	})
+
+	// Lose and re-win leadership
+	conceded, err := election.Concede()
+	require.Nil(t, err)
+	assert.True(t, conceded)
+
+	var lost bool
+	for {
+		select {
+		case e := <-events:
+			if !e.IsLeader {
+				lost = true
+				continue
+			}
+			if !lost {
+				continue
+			}
+		case <-ctx.Done():
+			require.FailNow(t, "did not regain leadership")
+		}
+		break
+	}
+	assert.Equal(t, true, election.IsLeader())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestElectionOnWatchStart(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
+	defer cancel()
+
+	watches := make(chan int64, 5)
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/watch-start-election",
+			Candidate: "me",
+		},
+		OnWatchStart: func(rev int64) {
+			watches <- rev
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	var first int64
+	select {
+	case first = <-watches:
+	case <-ctx.Done():
+		require.FailNow(t, "watch did not start")
+	}
+	assert.Len(t, watches, 0)
+
+	// Removing every candidate fails the watch, which is re-established with a new campaign
+	_, err = client.Delete(ctx, "/elections/watch-start-election", etcd.WithPrefix())
+	require.Nil(t, err)
+
+	select {
+	case rev := <-watches:
+		assert.True(t, rev > first)
+	case <-ctx.Done():
+		require.FailNow(t, "watch was not re-established")
+	}
+	require.Nil(t, election.WaitForLeader(ctx, "me"))
+	assert.Len(t, watches, 0)
+}
+
+func TestElectionSessionResets(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
+	defer cancel()
+
+	campaigns := make(chan string, 5)
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/session-resets-election",
+			Candidate: "me",
+		},
+		OnCampaign: func(key string, lease etcd.LeaseID) {
+			campaigns <- key
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+	assert.Equal(t, int64(0), election.SessionResets())
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-campaigns:
+		case <-ctx.Done():
+			require.FailNow(t, "campaign was not registered")
+		}
+		// Removing every candidate leaves no leader, which is a fatal error that resets the session
+		_, err := client.Delete(ctx, "/elections/session-resets-election", etcd.WithPrefix())
+		require.Nil(t, err)
+	}
+
+	select {
+	case <-campaigns:
+	case <-ctx.Done():
+		require.FailNow(t, "campaign was not registered")
+	}
+	assert.Equal(t, int64(3), election.SessionResets())
+	assert.Equal(t, int64(3), election.Stats().SessionResets)
+}
+
+func TestElectionHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	start := time.Unix(1500000000, 0)
+	clk := clock.NewFrozenClock(start)
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:          "/heartbeat-election",
+			Candidate:         "me",
+			HeartbeatInterval: time.Second,
+		},
+		Clock: clk,
+	})
+	require.Nil(t, err)
+	defer election.Close()
+	assert.Equal(t, true, election.IsLeader())
+	assert.True(t, election.LeaderHeartbeat().IsZero())
+
+	waitForHeartbeat := func(expected time.Time) {
+		for !election.LeaderHeartbeat().Equal(expected) {
+			select {
+			case <-time.After(time.Millisecond * 50):
+			case <-ctx.Done():
+				require.FailNow(t, "heartbeat not observed", "expected %s", expected)
+			}
+		}
+	}
+
+	require.True(t, clk.Wait4Scheduled(1, time.Second))
+	clk.Advance(time.Second)
+	waitForHeartbeat(start.Add(time.Second))
+
+	clk.Advance(time.Second)
+	waitForHeartbeat(start.Add(time.Second * 2))
+
+	// The heartbeat does not change the leader data
+	candidate, isSelf := election.Leader()
+	assert.Equal(t, "me", candidate)
+	assert.True(t, isSelf)
+}
+
+func TestElectionHeartbeatError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	// Fail every heartbeat write, the campaign is registered with a Txn
+	c, err := etcdutil.NewClient(&etcd.Config{
+		DialOptions: []grpc.DialOption{grpc.WithUnaryInterceptor(
+			func(ctx context.Context, method string, req, reply interface{},
+				cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+				if method == "/etcdserverpb.KV/Put" {
+					return errors.New("injected heartbeat failure")
+				}
+				return invoker(ctx, method, req, reply, cc, opts...)
+			})},
+	})
+	require.Nil(t, err)
+	defer c.Close()
+
+	events := make(chan etcdutil.Event, 10)
+	clk := clock.NewFrozenClock(time.Unix(1500000000, 0))
+	election, err := etcdutil.NewElection(ctx, c, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:          "/heartbeat-error-election",
+			Candidate:         "me",
+			HeartbeatInterval: time.Second,
+		},
+		EventObserver: func(e etcdutil.Event) {
+			events <- e
+		},
+		Clock: clk,
+	})
+	require.Nil(t, err)
+	defer election.Close()
+	assert.Equal(t, true, election.IsLeader())
+
+	require.True(t, clk.Wait4Scheduled(1, time.Second))
+	clk.Advance(time.Second)
+
+	for {
+		select {
+		case e := <-events:
+			if e.Err == nil {
+				continue
+			}
+			assert.Contains(t, e.Err.Error(), "injected heartbeat failure")
+		case <-ctx.Done():
+			require.FailNow(t, "heartbeat error not reported")
+		}
+		break
+	}
+
+	// We still hold our campaign key, so we are still leader
+	assert.Equal(t, true, election.IsLeader())
+}
+
+func TestElectionCandidateLikeHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	// Candidate data is only split at a heartbeat timestamp
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/candidate-like-heartbeat-election",
+			Candidate: "me\x00heartbeat",
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	candidate, isSelf := election.Leader()
+	assert.Equal(t, "me\x00heartbeat", candidate)
+	assert.True(t, isSelf)
+}
+
+func TestElectionTimeToLeadership(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	first, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/time-to-leadership-election",
+			Candidate: "first",
+		},
+	})
+	require.Nil(t, err)
+	defer first.Close()
+	assert.Equal(t, true, first.IsLeader())
+
+	clk := clock.NewFrozenClock(time.Unix(1500000000, 0))
+	second, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/time-to-leadership-election",
+			Candidate: "second",
+		},
+		Clock: clk,
+	})
+	require.Nil(t, err)
+	defer second.Close()
+
+	_, ok := second.TimeToLeadership()
+	assert.False(t, ok)
+
+	clk.Advance(time.Second * 5)
+	first.Close()
+	require.Nil(t, second.WaitForLeader(ctx, "second"))
+
+	d, ok := second.TimeToLeadership()
+	assert.True(t, ok)
+	assert.Equal(t, time.Second*5, d)
+}
+
+func TestElectionWaitForLeader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	first, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/wait-for-leader-election",
+			Candidate: "first",
+		},
+	})
+	require.Nil(t, err)
+	defer first.Close()
+
+	second, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/wait-for-leader-election",
+			Candidate: "second",
+		},
+	})
+	require.Nil(t, err)
+	defer second.Close()
+
+	// Returns immediately if the candidate is already leader
+	require.Nil(t, second.WaitForLeader(ctx, "first"))
+
+	waitErr := make(chan error, 1)
+	go func() {
+		waitErr <- second.WaitForLeader(ctx, "second")
+	}()
+
+	select {
+	case <-waitErr:
+		require.FailNow(t, "WaitForLeader returned before second became leader")
+	case <-time.After(time.Millisecond * 500):
+	}
+
+	first.Close()
+	require.Nil(t, <-waitErr)
+	assert.Equal(t, true, second.IsLeader())
+
+	// Gives up once the context is cancelled
+	shortCtx, shortCancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer shortCancel()
+	assert.Equal(t, context.DeadlineExceeded, second.WaitForLeader(shortCtx, "first"))
+}
+
+func TestElectionCreateRevisionTie(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	// Keys written in the same transaction share a create revision
+	prefix := "/elections/tie-election"
+	resp, err := client.Txn(ctx).Then(
+		etcd.OpPut(prefix+"bbb", "b-candidate"),
+		etcd.OpPut(prefix+"aaa", "a-candidate"),
+	).Commit()
+	require.Nil(t, err)
+	require.True(t, resp.Succeeded)
+	defer client.Delete(context.Background(), "/elections/tie-election", etcd.WithPrefix())
+
+	for i := 0; i < 3; i++ {
+		election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+			ElectionSettings: etcdutil.ElectionSettings{
+				Election:  "/tie-election",
+				Candidate: "me",
+			},
+		})
+		require.Nil(t, err)
+
+		candidate, isSelf := election.Leader()
+		assert.Equal(t, "a-candidate", candidate)
+		assert.False(t, isSelf)
+		election.Close()
+	}
+
+	// Rewriting the value of the leader, as a heartbeat does, must not change the leader
+	_, err = client.Put(ctx, prefix+"aaa", "a-candidate")
+	require.Nil(t, err)
+
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/tie-election",
+			Candidate: "me",
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	candidate, _ := election.Leader()
+	assert.Equal(t, "a-candidate", candidate)
+}
+
+func TestElectionResume(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	conf := etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/resume-election",
+			Candidate: "me",
+			FastClose: true,
+			TTL:       10,
+		},
+	}
+	first, err := etcdutil.NewElection(ctx, client, conf)
+	require.Nil(t, err)
+	require.Equal(t, true, first.IsLeader())
+
+	other, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/resume-election",
+			Candidate: "other",
+		},
+	})
+	require.Nil(t, err)
+	defer other.Close()
+
+	// Simulate a fast restart, which leaves our campaign key and lease behind
+	stats := first.Stats()
+	first.Close()
+
+	conf.ResumeKey = stats.CampaignKey
+	conf.ResumeLease = stats.SessionLeaseID
+	start := time.Now()
+	resumed, err := etcdutil.NewElection(ctx, client, conf)
+	require.Nil(t, err)
+	defer resumed.Close()
+
+	assert.Equal(t, true, resumed.IsLeader())
+	assert.Equal(t, false, other.IsLeader())
+	assert.True(t, time.Since(start) < time.Second*2)
+	assert.Equal(t, stats.CampaignKey, resumed.Stats().CampaignKey)
+	assert.Equal(t, stats.SessionLeaseID, resumed.Stats().SessionLeaseID)
+}
+
+func TestElectionResumeExpiredLease(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	lease, err := client.Grant(ctx, 10)
+	require.Nil(t, err)
+	_, err = client.Revoke(ctx, lease.ID)
+	require.Nil(t, err)
+
+	// Falls back to registering a new campaign
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:    "/resume-expired-election",
+			Candidate:   "me",
+			ResumeKey:   fmt.Sprintf("/elections/resume-expired-election%x", lease.ID),
+			ResumeLease: lease.ID,
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	assert.Equal(t, true, election.IsLeader())
+	assert.NotEqual(t, lease.ID, election.Stats().SessionLeaseID)
+}
+
+func TestElectionDuplicateCampaignKey(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	// Simulate etcd reusing a lease ID by pre-creating the campaign key for the lease
+	lease, err := client.Grant(ctx, 10)
+	require.Nil(t, err)
+	key := fmt.Sprintf("/elections/duplicate-election%x", lease.ID)
+	_, err = client.Put(ctx, key, "someone-else", etcd.WithLease(lease.ID))
+	require.Nil(t, err)
+
+	warnings := make(chan string, 5)
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:    "/duplicate-election",
+			Candidate:   "me",
+			ResumeLease: lease.ID,
+		},
+		EventObserver: func(e etcdutil.Event) {
+			if e.Warn != "" {
+				warnings <- e.Warn
+			}
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	select {
+	case warn := <-warnings:
+		assert.Contains(t, warn, key)
+	case <-ctx.Done():
+		require.FailNow(t, "no warning for duplicate campaign key")
+	}
+	assert.Equal(t, int64(1), election.Stats().DuplicateCampaignKeys)
+
+	// We assumed ownership of the key
+	assert.Equal(t, true, election.IsLeader())
+	candidate, _ := election.Leader()
+	assert.Equal(t, "me", candidate)
+}
+
+func TestElectionString(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/string-election",
+			Candidate: "me",
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	str := election.String()
+	assert.Contains(t, str, "name: /elections/string-election")
+	assert.Contains(t, str, "candidate: me")
+	assert.Contains(t, str, "isLeader: true")
+	assert.Contains(t, str, "leaderData: me")
+	assert.Contains(t, str, fmt.Sprintf("leaseID: %x", int64(election.Stats().SessionLeaseID)))
+}
+
+func TestElectionOnCampaign(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	type campaign struct {
+		key   string
+		lease etcd.LeaseID
+	}
+	campaigns := make(chan campaign, 5)
+
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/my-election",
+			Candidate: "me",
+		},
+		OnCampaign: func(key string, lease etcd.LeaseID) {
+			campaigns <- campaign{key: key, lease: lease}
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	first := <-campaigns
+	assert.Equal(t, election.Stats().CampaignKey, first.key)
+	assert.NotEqual(t, etcdutil.NoLease, first.lease)
+
+	// Conceding resets the session, which registers the campaign again with a new lease
+	conceded, err := election.Concede()
+	require.Nil(t, err)
+	assert.True(t, conceded)
+
+	select {
+	case second := <-campaigns:
+		assert.NotEqual(t, first.lease, second.lease)
+		assert.NotEqual(t, first.key, second.key)
+	case <-ctx.Done():
+		require.FailNow(t, "timeout waiting for the campaign to be registered again")
+	}
+}
+
+func TestElectionFastClose(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
+	defer cancel()
+
+	// Record the RPCs which would remove our campaign key
+	var mutex sync.Mutex
+	var removals []string
+	c, err := etcdutil.NewClient(&etcd.Config{
+		DialOptions: []grpc.DialOption{grpc.WithUnaryInterceptor(
+			func(ctx context.Context, method string, req, reply interface{},
+				cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+				if method == "/etcdserverpb.KV/DeleteRange" || method == "/etcdserverpb.Lease/LeaseRevoke" {
+					mutex.Lock()
+					removals = append(removals, method)
+					mutex.Unlock()
+				}
+				return invoker(ctx, method, req, reply, cc, opts...)
+			})},
+	})
+	require.Nil(t, err)
+	defer c.Close()
+
+	election, err := etcdutil.NewElection(ctx, c, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/fast-close-election",
+			Candidate: "me",
+			FastClose: true,
+			TTL:       2,
+		},
+	})
+	require.Nil(t, err)
+	key := election.Stats().CampaignKey
+
+	election.Close()
+	assert.Equal(t, false, election.IsLeader())
+	mutex.Lock()
+	assert.Empty(t, removals)
+	mutex.Unlock()
+
+	// The key remains until our lease expires
+	resp, err := client.Get(ctx, key)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), resp.Count)
+
+	for {
+		resp, err := client.Get(ctx, key)
+		require.Nil(t, err)
+		if resp.Count == 0 {
+			break
+		}
+		select {
+		case <-time.After(time.Millisecond * 250):
+		case <-ctx.Done():
+			require.FailNow(t, "campaign key did not expire with our lease")
+		}
+	}
+}
+
+func TestElectionNonBlocking(t *testing.T) {
+	// Start the election while etcd is unavailable
+	proxy.Stop()
+	defer proxy.Start()
+
+	start := time.Now()
+	election, err := etcdutil.NewElection(context.Background(), client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:    "/non-blocking-election",
+			Candidate:   "me",
+			NonBlocking: true,
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, false, election.IsLeader())
+
+	require.Nil(t, proxy.Start())
+
+	// Leadership is eventually acquired once etcd is available
+	deadline := time.Now().Add(time.Second * 20)
+	for !election.IsLeader() {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "timeout waiting for leadership")
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+}
+
+func TestElectionCodec(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	events := make(chan etcdutil.Event, 5)
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election: "/codec-election",
+		},
+		Codec:          etcdutil.JSONCodec{Value: candidate{}},
+		CandidateValue: candidate{Host: "worker-n01", Zone: "us-east-1a"},
+		EventObserver: func(e etcdutil.Event) {
+			events <- e
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	e := <-events
+	require.Nil(t, e.Err)
+	assert.True(t, e.IsLeader)
+	assert.Equal(t, `{"host":"worker-n01","zone":"us-east-1a"}`, e.LeaderData)
+	assert.Equal(t, &candidate{Host: "worker-n01", Zone: "us-east-1a"}, e.LeaderValue)
+}
+
+func TestElectionRunAsLeader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/run-as-leader-election",
+			Candidate: "me",
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	started := make(chan struct{}, 5)
+	stopped := make(chan struct{}, 5)
+	runCtx, cancelRun := context.WithCancel(ctx)
+	result := make(chan error, 1)
+	go func() {
+		result <- election.RunAsLeader(runCtx, func(ctx context.Context) error {
+			assert.True(t, election.IsLeader())
+			started <- struct{}{}
+			<-ctx.Done()
+			stopped <- struct{}{}
+			return ctx.Err()
+		})
+	}()
+
+	wait := func(ch chan struct{}, msg string) {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			require.FailNow(t, msg)
+		}
+	}
+	wait(started, "fn was not called while leader")
+
+	// Demotion cancels the context of fn, which is called again once re-elected
+	conceded, err := election.Concede()
+	require.Nil(t, err)
+	assert.True(t, conceded)
+	wait(stopped, "fn was not cancelled on demotion")
+	wait(started, "fn was not called after re-election")
+
+	cancelRun()
+	wait(stopped, "fn was not cancelled with the context")
+	assert.Equal(t, context.Canceled, <-result)
+}
+
+func TestElectionReadyFunc(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var ready int32
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:    "/ready-election",
+			Candidate:   "me",
+			NonBlocking: true,
+		},
+		ReadyFunc: func() bool {
+			return atomic.LoadInt32(&ready) == 1
+		},
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	// Leadership is deferred until ready
+	time.Sleep(time.Second)
+	assert.Equal(t, false, election.IsLeader())
+	assert.Equal(t, "", election.Stats().CampaignKey)
+
+	atomic.StoreInt32(&ready, 1)
+	require.Nil(t, election.WaitForLeader(ctx, "me"))
+	assert.Equal(t, true, election.IsLeader())
+}
+
+func TestElectionHostnameFunc(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election: "/hostname-election",
+		},
+		HostnameFunc: func() (string, error) { return "pod-1", nil },
+	})
+	require.Nil(t, err)
+	defer election.Close()
+
+	assert.Equal(t, "pod-1", election.Stats().CurrentLeaderData)
+}
+
+func TestElectionInitialTimeout(t *testing.T) {
+	proxy.Stop()
+	defer proxy.Start()
+
+	start := time.Now()
+	_, err := etcdutil.NewElection(context.Background(), client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:       "/initial-timeout-election",
+			Candidate:      "me",
+			InitialTimeout: time.Millisecond * 500,
+		},
+	})
+	require.NotNil(t, err)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second*2)
+}
+
+func TestElectionOpTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
+	defer cancel()
+
+	// Simulate an unresponsive etcd by blocking every Range until it is abandoned
+	c, err := etcdutil.NewClient(&etcd.Config{
+		DialOptions: []grpc.DialOption{grpc.WithUnaryInterceptor(
+			func(ctx context.Context, method string, req, reply interface{},
+				cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+				if method == "/etcdserverpb.KV/Range" {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return invoker(ctx, method, req, reply, cc, opts...)
+			})},
+	})
+	require.Nil(t, err)
+	defer c.Close()
+
+	start := time.Now()
+	_, err = etcdutil.NewElection(ctx, c, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/op-timeout-election",
+			Candidate: "me",
+			OpTimeout: time.Millisecond * 500,
+			TTL:       10,
+		},
+	})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "while querying for current leader")
+	assert.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+	assert.True(t, time.Since(start) < time.Second*5)
+}
+
+func TestElectionLeaderPolicy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	// Selects the candidate with the highest version
+	highestVersion := func(candidates []etcdutil.Candidate) *etcdutil.Candidate {
+		var highest *etcdutil.Candidate
+		for i := range candidates {
+			if highest == nil || versionLess(highest.Data, candidates[i].Data) {
+				highest = &candidates[i]
+			}
+		}
+		return highest
+	}
+
+	var elections []*etcdutil.Election
+	for _, version := range []string{"1.9.0", "1.10.0", "1.2.0"} {
+		election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+			ElectionSettings: etcdutil.ElectionSettings{
+				Election:  "/policy-election",
+				Candidate: version,
+			},
+			LeaderPolicy: highestVersion,
+		})
+		require.Nil(t, err)
+		defer election.Close()
+		elections = append(elections, election)
+	}
+
+	// Every candidate agrees the highest version leads, regardless of when it joined
+	for _, election := range elections {
+		require.Nil(t, election.WaitForLeader(ctx, "1.10.0"))
+	}
+	assert.Equal(t, false, elections[0].IsLeader())
+	assert.Equal(t, true, elections[1].IsLeader())
+	assert.Equal(t, false, elections[2].IsLeader())
+
+	// Once the leader leaves the policy selects the next highest version
+	elections[1].Close()
+	require.Nil(t, elections[0].WaitForLeader(ctx, "1.9.0"))
+	assert.Equal(t, true, elections[0].IsLeader())
+}
+
+// versionLess returns true if the dotted version a is lower than b
+func versionLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, _ := strconv.Atoi(as[i])
+		bn, _ := strconv.Atoi(bs[i])
+		if an != bn {
+			return an < bn
+		}
+	}
+	return len(as) < len(bs)
+}
+
+func TestElectionPriority(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	low, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/priority-election",
+			Candidate: "low",
+			Priority:  10,
+		},
+	})
+	require.Nil(t, err)
+	defer low.Close()
+	assert.Equal(t, true, low.IsLeader())
+
+	lowChan := make(chan etcdutil.Event, 5)
+	low.AddObserver("test", func(e etcdutil.Event) {
+		lowChan <- e
+	})
+
+	// A preferred candidate joining later takes leadership
+	high, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/priority-election",
+			Candidate: "high",
+			Priority:  1,
+		},
+	})
+	require.Nil(t, err)
+	defer high.Close()
+	assert.Equal(t, true, high.IsLeader())
+
+	select {
+	case e := <-lowChan:
+		assert.Equal(t, false, e.IsLeader)
+		assert.Equal(t, "high", e.LeaderData)
+	case <-ctx.Done():
+		require.FailNow(t, "timeout waiting for leadership change")
+	}
+	assert.Equal(t, false, low.IsLeader())
+}
+
+type recordedSpan struct {
+	name   string
+	events []string
+	err    error
+	ended  bool
+}
+
+// spanRecorder is an in memory etcdutil.Tracer
+type spanRecorder struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *spanRecorder) Start(ctx context.Context, name string) (context.Context, etcdutil.Span) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	s := &recordedSpan{name: name}
+	r.spans = append(r.spans, s)
+	return ctx, &recorderSpan{r: r, s: s}
+}
+
+func (r *spanRecorder) named(name string) []recordedSpan {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var result []recordedSpan
+	for _, s := range r.spans {
+		if s.name == name {
+			result = append(result, *s)
+		}
+	}
+	return result
+}
+
+type recorderSpan struct {
+	r *spanRecorder
+	s *recordedSpan
+}
+
+func (s *recorderSpan) AddEvent(name string, attrs map[string]string) {
+	s.r.mutex.Lock()
+	defer s.r.mutex.Unlock()
+	s.s.events = append(s.s.events, name)
+}
+
+func (s *recorderSpan) RecordError(err error) {
+	s.r.mutex.Lock()
+	defer s.r.mutex.Unlock()
+	s.s.err = err
+}
+
+func (s *recorderSpan) End() {
+	s.r.mutex.Lock()
+	defer s.r.mutex.Unlock()
+	s.s.ended = true
+}
+
+func TestElectionTracer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	recorder := &spanRecorder{}
+	election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+		ElectionSettings: etcdutil.ElectionSettings{
+			Election:  "/my-election",
+			Candidate: "me",
+		},
+		Tracer: recorder,
+	})
+	require.Nil(t, err)
+	require.Equal(t, true, election.IsLeader())
+
+	registered := recorder.named("etcdutil.Election.registerCampaign")
+	require.Equal(t, 1, len(registered))
+	assert.True(t, registered[0].ended)
+	assert.Nil(t, registered[0].err)
+
+	election.Close()
+
+	watched := recorder.named("etcdutil.Election.watchCampaign")
+	require.Equal(t, 1, len(watched))
+	assert.True(t, watched[0].ended)
+	assert.Equal(t, []string{"leader change", "leader change"}, watched[0].events)
+	assert.Equal(t, 1, len(recorder.named("etcdutil.Election.withDrawCampaign")))
+}
+
+func TestManualElector(t *testing.T) {
+	var events []etcdutil.Event
+	elector := etcdutil.NewManualElector(func(e etcdutil.Event) {
+		events = append(events, e)
+	})
+	assert.Equal(t, false, elector.IsLeader())
+
+	elector.SetLeader(true)
+	assert.Equal(t, true, elector.IsLeader())
+
+	// Setting the same leadership again does not notify
+	elector.SetLeader(true)
+
+	conceded, err := elector.Concede()
+	require.Nil(t, err)
+	assert.True(t, conceded)
+	assert.Equal(t, false, elector.IsLeader())
+
+	conceded, err = elector.Concede()
+	require.Nil(t, err)
+	assert.False(t, conceded)
+
+	elector.SetLeader(true)
+	elector.Close()
+	assert.Equal(t, false, elector.IsLeader())
+
+	// No events follow once done
+	elector.SetLeader(true)
+	assert.Equal(t, false, elector.IsLeader())
+
+	assert.Equal(t, []etcdutil.Event{
+		{IsLeader: true},
+		{IsLeader: false},
+		{IsLeader: true},
+		{IsLeader: false, IsDone: true},
+	}, events)
+}
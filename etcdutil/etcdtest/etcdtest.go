@@ -0,0 +1,90 @@
+// Package etcdtest starts an embedded etcd server for tests, such that code
+// using etcd can be tested without running etcd separately.
+package etcdtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/embed"
+)
+
+// StartEmbedded starts a single member etcd server listening on free ports of
+// localhost with its data in a temporary directory, and returns a client connected
+// to it. The returned function closes the client, stops the server and removes its
+// data, it must be called once the test is over.
+//
+//	func TestElection(t *testing.T) {
+//		client, teardown := etcdtest.StartEmbedded(t)
+//		defer teardown()
+//
+//		election, err := etcdutil.NewElection(ctx, client, etcdutil.ElectionConfig{
+//			ElectionSettings: etcdutil.ElectionSettings{
+//				Election:  "my-election",
+//				Candidate: "me",
+//			},
+//		})
+//	}
+func StartEmbedded(t testing.TB) (*etcd.Client, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "etcdtest")
+	if err != nil {
+		t.Fatalf("while creating etcd data dir: %s", err)
+	}
+
+	clientURL, peerURL := freeURL(t), freeURL(t)
+	cfg := embed.NewConfig()
+	cfg.Dir = dir
+	cfg.LCUrls, cfg.ACUrls = []url.URL{clientURL}, []url.URL{clientURL}
+	cfg.LPUrls, cfg.APUrls = []url.URL{peerURL}, []url.URL{peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	server, err := embed.StartEtcd(cfg)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("while starting embedded etcd: %s", err)
+	}
+
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(time.Second * 30):
+		server.Close()
+		os.RemoveAll(dir)
+		t.Fatalf("embedded etcd did not become ready")
+	}
+
+	client, err := etcd.New(etcd.Config{
+		Endpoints:   []string{clientURL.String()},
+		DialTimeout: time.Second * 5,
+	})
+	if err != nil {
+		server.Close()
+		os.RemoveAll(dir)
+		t.Fatalf("while connecting to embedded etcd: %s", err)
+	}
+
+	return client, func() {
+		client.Close()
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// freeURL returns an http url for a port on localhost which is free at the time of the call
+func freeURL(t testing.TB) url.URL {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("while finding a free port: %s", err)
+	}
+	defer l.Close()
+	return url.URL{Scheme: "http", Host: fmt.Sprintf("127.0.0.1:%d", l.Addr().(*net.TCPAddr).Port)}
+}
@@ -0,0 +1,27 @@
+package etcdtest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mailgun/holster/etcdutil/etcdtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartEmbedded(t *testing.T) {
+	client, teardown := etcdtest.StartEmbedded(t)
+	defer teardown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	_, err := client.Put(ctx, "/etcdtest/key", "value")
+	require.Nil(t, err)
+
+	resp, err := client.Get(ctx, "/etcdtest/key")
+	require.Nil(t, err)
+	require.Len(t, resp.Kvs, 1)
+	assert.Equal(t, "value", string(resp.Kvs[0].Value))
+}
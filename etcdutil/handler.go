@@ -0,0 +1,25 @@
+package etcdutil
+
+import (
+	"net/http"
+)
+
+// LeaderOnly returns a handler which serves requests with `h` only while `e` is leader,
+// and otherwise responds with 503 Service Unavailable so the client can retry against
+// another instance.
+//
+//	mux.Handle("/admin/compact", etcdutil.LeaderOnly(election, compactHandler))
+func LeaderOnly(e LeaderElector, h http.Handler) http.Handler {
+	return LeaderOnlyWithStatus(e, http.StatusServiceUnavailable, h)
+}
+
+// LeaderOnlyWithStatus is like LeaderOnly but responds with `code` while we are not leader
+func LeaderOnlyWithStatus(e LeaderElector, code int, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !e.IsLeader() {
+			http.Error(w, "not leader", code)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,46 @@
+package etcdutil_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mailgun/holster/etcdutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type neverLeaderMock struct{}
+
+func (s *neverLeaderMock) IsLeader() bool         { return false }
+func (s *neverLeaderMock) Concede() (bool, error) { return false, nil }
+func (s *neverLeaderMock) Close()                 {}
+
+func TestLeaderOnly(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		name    string
+		handler http.Handler
+		code    int
+	}{{
+		name:    "leader",
+		handler: etcdutil.LeaderOnly(&etcdutil.AlwaysLeaderMock{}, ok),
+		code:    http.StatusOK,
+	}, {
+		name:    "not leader",
+		handler: etcdutil.LeaderOnly(&neverLeaderMock{}, ok),
+		code:    http.StatusServiceUnavailable,
+	}, {
+		name:    "not leader with status",
+		handler: etcdutil.LeaderOnlyWithStatus(&neverLeaderMock{}, http.StatusMisdirectedRequest, ok),
+		code:    http.StatusMisdirectedRequest,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tc.handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package etcdutil
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/mailgun/holster"
+	"github.com/pkg/errors"
+)
+
+type MutexConfig struct {
+	// The name of the lock, all Mutexes with the same name exclude each other
+	Name string
+	// The TTL of the lease which holds the lock. If we lose connectivity with etcd the
+	// lock is released once the TTL expires. (Default is 30 seconds)
+	TTL int64
+}
+
+// Mutex is a distributed lock built on a Session. Waiters are queued in the order
+// they called Lock() by the create revision of their key, and a lock held by a
+// process that loses connectivity with etcd is released once its lease expires.
+//
+// A single Mutex holds at most one lock at a time and should not be shared by
+// goroutines attempting to lock it concurrently, create a Mutex for each instead.
+// Work protected by the lock should stop once Done() is closed, as the lock is
+// no longer held once the session loses its lease.
+type Mutex struct {
+	client  *etcd.Client
+	session *Session
+	prefix  string
+	timeout time.Duration
+
+	mutex   sync.Mutex
+	leaseID etcd.LeaseID
+	// Closed once the session has been granted a lease
+	leaseReady chan struct{}
+	key        string
+	// The lease our key is attached to
+	keyLease etcd.LeaseID
+	// Closed once the lock we hold is lost or released
+	done chan struct{}
+}
+
+// NewMutex creates a new Mutex and starts a session to hold the lease
+// used by subsequent calls to Lock()
+//
+//	mutex, err := etcdutil.NewMutex(client, etcdutil.MutexConfig{Name: "my-lock"})
+//	if err != nil {
+//		return err
+//	}
+//	defer mutex.Close()
+//
+//	if err := mutex.Lock(ctx); err != nil {
+//		return err
+//	}
+//	defer mutex.Unlock(context.Background())
+func NewMutex(client *etcd.Client, conf MutexConfig) (*Mutex, error) {
+	if conf.Name == "" {
+		return nil, errors.New("MutexConfig.Name can not be empty")
+	}
+	holster.SetDefault(&conf.TTL, int64(30))
+
+	m := &Mutex{
+		client:     client,
+		prefix:     path.Join("/mutexes", conf.Name) + "/",
+		timeout:    time.Duration(conf.TTL) * time.Second,
+		leaseID:    NoLease,
+		leaseReady: make(chan struct{}),
+	}
+
+	var err error
+	if m.session, err = NewSession(client, SessionConfig{
+		Observer: m.onSessionChange,
+		TTL:      conf.TTL,
+	}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Mutex) onSessionChange(leaseID etcd.LeaseID, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if leaseID == NoLease {
+		if m.leaseID != NoLease {
+			m.leaseReady = make(chan struct{})
+		}
+		m.leaseID = NoLease
+		// Our key expires with the lease, so any lock we hold is lost
+		m.closeDone()
+		return
+	}
+
+	if m.leaseID == NoLease {
+		close(m.leaseReady)
+	}
+	m.leaseID = leaseID
+}
+
+// waitForLease returns the current lease, waiting for the session to gain one if needed
+func (m *Mutex) waitForLease(ctx context.Context) (etcd.LeaseID, error) {
+	for {
+		m.mutex.Lock()
+		leaseID, ready := m.leaseID, m.leaseReady
+		m.mutex.Unlock()
+
+		if leaseID != NoLease {
+			return leaseID, nil
+		}
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return NoLease, errors.Wrap(ctx.Err(), "while waiting for a lease")
+		}
+	}
+}
+
+// Lock blocks until the lock is acquired or the context is cancelled. An error is
+// returned if the lease our key is attached to was lost while waiting for the lock.
+func (m *Mutex) Lock(ctx context.Context) error {
+	myRev, ownerRev, err := m.enqueue(ctx)
+	if err != nil {
+		return err
+	}
+
+	if myRev != ownerRev {
+		if err := m.waitForPredecessors(ctx, myRev); err != nil {
+			m.dequeue()
+			return err
+		}
+	}
+
+	if err := m.acquired(); err != nil {
+		m.dequeue()
+		return err
+	}
+	return nil
+}
+
+// TryLock acquires the lock only if it is not currently held, returns true if the lock was acquired
+func (m *Mutex) TryLock(ctx context.Context) (bool, error) {
+	myRev, ownerRev, err := m.enqueue(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if myRev == ownerRev {
+		if err := m.acquired(); err != nil {
+			m.dequeue()
+			return false, err
+		}
+		return true, nil
+	}
+
+	m.dequeue()
+	return false, nil
+}
+
+// Unlock releases the lock. Unlocking a Mutex that is not locked does nothing.
+func (m *Mutex) Unlock(ctx context.Context) error {
+	m.mutex.Lock()
+	key := m.key
+	m.key = ""
+	m.closeDone()
+	m.mutex.Unlock()
+
+	if key == "" {
+		return nil
+	}
+
+	if _, err := m.client.Delete(ctx, key); err != nil {
+		return errors.Wrapf(err, "while releasing lock '%s'", key)
+	}
+	return nil
+}
+
+// Done returns a channel which is closed once the lock acquired by Lock() or TryLock() is
+// no longer held, either because it was released or because the session lost the lease
+// the lock is attached to. The returned channel is already closed if the lock is not held.
+func (m *Mutex) Done() <-chan struct{} {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.done == nil {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	return m.done
+}
+
+// acquired records that we hold the lock, unless the lease our key is attached to was
+// lost in the meantime, in which case our key expired and we do not hold the lock
+func (m *Mutex) acquired() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.leaseID != m.keyLease {
+		return errors.Errorf("lease of lock key '%s' was lost while acquiring the lock", m.key)
+	}
+	m.done = make(chan struct{})
+	return nil
+}
+
+// closeDone closes the Done() channel of the lock we hold, the caller must hold m.mutex
+func (m *Mutex) closeDone() {
+	if m.done != nil {
+		close(m.done)
+		m.done = nil
+	}
+}
+
+// Close releases the lock if held and revokes the lease used by the Mutex
+func (m *Mutex) Close() {
+	m.session.Close()
+}
+
+// enqueue creates our key under the lock prefix if it doesn't already exist and returns the
+// create revision of our key and the create revision of the key which currently holds the lock
+func (m *Mutex) enqueue(ctx context.Context) (int64, int64, error) {
+	leaseID, err := m.waitForLease(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	key := fmt.Sprintf("%s%x", m.prefix, leaseID)
+	owner := etcd.OpGet(m.prefix, etcd.WithFirstCreate()...)
+	resp, err := m.client.Txn(ctx).
+		If(etcd.Compare(etcd.CreateRevision(key), "=", 0)).
+		Then(etcd.OpPut(key, "", etcd.WithLease(leaseID)), owner).
+		Else(etcd.OpGet(key), owner).
+		Commit()
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "while creating lock key '%s'", key)
+	}
+
+	m.mutex.Lock()
+	m.key = key
+	m.keyLease = leaseID
+	m.mutex.Unlock()
+
+	myRev := resp.Header.Revision
+	if !resp.Succeeded {
+		myRev = resp.Responses[0].GetResponseRange().Kvs[0].CreateRevision
+	}
+
+	ownerRev := myRev
+	if kvs := resp.Responses[1].GetResponseRange().Kvs; len(kvs) != 0 {
+		ownerRev = kvs[0].CreateRevision
+	}
+	return myRev, ownerRev, nil
+}
+
+// dequeue removes our key from the lock queue
+func (m *Mutex) dequeue() {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	m.Unlock(ctx)
+}
+
+// waitForPredecessors waits until all keys created before `rev` have been deleted
+func (m *Mutex) waitForPredecessors(ctx context.Context, rev int64) error {
+	opts := append(etcd.WithLastCreate(), etcd.WithMaxCreateRev(rev-1))
+	for {
+		resp, err := m.client.Get(ctx, m.prefix, opts...)
+		if err != nil {
+			return errors.Wrap(err, "while querying lock queue")
+		}
+
+		if len(resp.Kvs) == 0 {
+			return nil
+		}
+
+		if err := m.waitForDelete(ctx, string(resp.Kvs[0].Key), resp.Header.Revision+1); err != nil {
+			return err
+		}
+	}
+}
+
+func (m *Mutex) waitForDelete(ctx context.Context, key string, rev int64) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for resp := range m.client.Watch(etcd.WithRequireLeader(watchCtx), key, etcd.WithRev(rev)) {
+		if err := resp.Err(); err != nil {
+			return errors.Wrapf(err, "while watching lock key '%s'", key)
+		}
+		for _, event := range resp.Events {
+			if event.Type == etcd.EventTypeDelete {
+				return nil
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return errors.Errorf("watch on lock key '%s' closed unexpectedly", key)
+}
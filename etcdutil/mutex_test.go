@@ -0,0 +1,221 @@
+package etcdutil_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/mailgun/holster/etcdutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMutex(t *testing.T, name string) *etcdutil.Mutex {
+	m, err := etcdutil.NewMutex(client, etcdutil.MutexConfig{Name: name, TTL: 5})
+	require.Nil(t, err)
+	return m
+}
+
+func TestMutexExclusion(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
+	defer cancel()
+
+	var holders, max int
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < 2; i++ {
+		m := newMutex(t, "exclusion")
+		defer m.Close()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				require.Nil(t, m.Lock(ctx))
+
+				mutex.Lock()
+				holders++
+				if holders > max {
+					max = holders
+				}
+				mutex.Unlock()
+
+				time.Sleep(time.Millisecond * 10)
+
+				mutex.Lock()
+				holders--
+				mutex.Unlock()
+
+				require.Nil(t, m.Unlock(ctx))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, max)
+}
+
+func TestMutexFairOrdering(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	first := newMutex(t, "ordering")
+	defer first.Close()
+	require.Nil(t, first.Lock(ctx))
+
+	acquired := make(chan string, 2)
+	var wg sync.WaitGroup
+	for _, name := range []string{"second", "third"} {
+		m := newMutex(t, "ordering")
+		defer m.Close()
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			require.Nil(t, m.Lock(ctx))
+			acquired <- name
+			require.Nil(t, m.Unlock(ctx))
+		}(name)
+
+		// Ensure each waiter is queued before the next
+		time.Sleep(time.Millisecond * 500)
+	}
+
+	require.Nil(t, first.Unlock(ctx))
+	wg.Wait()
+
+	assert.Equal(t, "second", <-acquired)
+	assert.Equal(t, "third", <-acquired)
+}
+
+func TestMutexTryLock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	m1 := newMutex(t, "try-lock")
+	defer m1.Close()
+	m2 := newMutex(t, "try-lock")
+	defer m2.Close()
+
+	ok, err := m1.TryLock(ctx)
+	require.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = m2.TryLock(ctx)
+	require.Nil(t, err)
+	assert.False(t, ok)
+
+	require.Nil(t, m1.Unlock(ctx))
+
+	ok, err = m2.TryLock(ctx)
+	require.Nil(t, err)
+	assert.True(t, ok)
+	require.Nil(t, m2.Unlock(ctx))
+}
+
+func TestMutexDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	m := newMutex(t, "done")
+	defer m.Close()
+
+	// Not held
+	select {
+	case <-m.Done():
+	default:
+		t.Fatal("Done() not closed before the lock is acquired")
+	}
+
+	require.Nil(t, m.Lock(ctx))
+	done := m.Done()
+	select {
+	case <-done:
+		t.Fatal("Done() closed while the lock is held")
+	default:
+	}
+
+	require.Nil(t, m.Unlock(ctx))
+	select {
+	case <-done:
+	default:
+		t.Fatal("Done() not closed after Unlock()")
+	}
+}
+
+func TestMutexDoneLeaseLost(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	m, err := etcdutil.NewMutex(client, etcdutil.MutexConfig{Name: "done-lease-lost", TTL: 1})
+	require.Nil(t, err)
+	defer m.Close()
+
+	require.Nil(t, m.Lock(ctx))
+
+	// Interrupt the connection until the session gives up the lease
+	proxy.Stop()
+	defer func() { require.Nil(t, proxy.Start()) }()
+
+	select {
+	case <-m.Done():
+	case <-ctx.Done():
+		require.FailNow(t, "Done() not closed after the lease was lost")
+	}
+}
+
+func TestMutexLockLeaseLost(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	m1 := newMutex(t, "lock-lease-lost")
+	defer m1.Close()
+	m2 := newMutex(t, "lock-lease-lost")
+
+	require.Nil(t, m1.Lock(ctx))
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- m2.Lock(ctx)
+	}()
+
+	// Wait for the second mutex to queue behind the first
+	for {
+		resp, err := client.Get(ctx, "/mutexes/lock-lease-lost/", etcd.WithPrefix(), etcd.WithCountOnly())
+		require.Nil(t, err)
+		if resp.Count == 2 {
+			break
+		}
+		select {
+		case <-time.After(time.Millisecond * 50):
+		case <-ctx.Done():
+			require.FailNow(t, "second mutex did not queue for the lock")
+		}
+	}
+
+	// Losing the lease while queued means the lock is not acquired once it is released
+	m2.Close()
+	require.Nil(t, m1.Unlock(ctx))
+
+	select {
+	case err := <-errs:
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), "was lost while acquiring the lock")
+	case <-ctx.Done():
+		require.FailNow(t, "Lock() did not return")
+	}
+	select {
+	case <-m2.Done():
+	default:
+		t.Fatal("Done() not closed for a lock which was not acquired")
+	}
+}
+
+func TestNewMutexEmptyName(t *testing.T) {
+	_, err := etcdutil.NewMutex(client, etcdutil.MutexConfig{})
+	require.NotNil(t, err)
+	assert.Equal(t, "MutexConfig.Name can not be empty", err.Error())
+}
@@ -0,0 +1,114 @@
+package etcdutil
+
+import (
+	"context"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/mailgun/holster"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Idempotent etcd methods which are safe to retry
+var retryMethods = map[string]bool{
+	"/etcdserverpb.KV/Range":    true,
+	"/etcdserverpb.Watch/Watch": true,
+}
+
+type RetryConfig struct {
+	// The maximum number of attempts made for each call (Default is 3)
+	Attempts int
+	// The timeout applied to each unary attempt. Zero means attempts are only
+	// bound by the context of the caller. Watch streams are long lived and
+	// are never given a per call timeout.
+	PerCallTimeout time.Duration
+}
+
+// NewClientWithRetry is just like NewClient but installs gRPC interceptors which
+// retry idempotent reads (Get and Watch creation) that fail with a transient
+// `codes.Unavailable` error, as happens during etcd leader elections and restarts.
+// The provided config is not modified. Only the creation of a watch stream is retried,
+// errors received on an established stream are returned to the caller as usual.
+//
+//	client, err := etcdutil.NewClientWithRetry(nil, etcdutil.RetryConfig{
+//		Attempts:       5,
+//		PerCallTimeout: time.Second * 2,
+//	})
+func NewClientWithRetry(cfg *etcd.Config, conf RetryConfig) (*etcd.Client, error) {
+	holster.SetDefault(&cfg, &etcd.Config{})
+
+	// Copy the config and dial options, such that the interceptors are not added to the caller's config
+	c := *cfg
+	c.DialOptions = make([]grpc.DialOption, len(cfg.DialOptions), len(cfg.DialOptions)+2)
+	copy(c.DialOptions, cfg.DialOptions)
+	c.DialOptions = append(c.DialOptions,
+		grpc.WithUnaryInterceptor(RetryUnaryInterceptor(conf)),
+		grpc.WithStreamInterceptor(RetryStreamInterceptor(conf)))
+	return NewClient(&c)
+}
+
+// RetryUnaryInterceptor returns a grpc.UnaryClientInterceptor which retries idempotent
+// etcd reads that failed with `codes.Unavailable`
+func RetryUnaryInterceptor(conf RetryConfig) grpc.UnaryClientInterceptor {
+	holster.SetDefault(&conf.Attempts, 3)
+
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+
+		if !retryMethods[method] {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		return retry(ctx, conf.Attempts, func() error {
+			if conf.PerCallTimeout == 0 {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			}
+			ctx, cancel := context.WithTimeout(ctx, conf.PerCallTimeout)
+			defer cancel()
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+	}
+}
+
+// RetryStreamInterceptor returns a grpc.StreamClientInterceptor which retries the creation
+// of etcd watch streams that failed with `codes.Unavailable`. Errors received once the
+// stream is established are not retried.
+func RetryStreamInterceptor(conf RetryConfig) grpc.StreamClientInterceptor {
+	holster.SetDefault(&conf.Attempts, 3)
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+		method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+
+		if !retryMethods[method] {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		var stream grpc.ClientStream
+		err := retry(ctx, conf.Attempts, func() error {
+			var err error
+			stream, err = streamer(ctx, desc, cc, method, opts...)
+			return err
+		})
+		return stream, err
+	}
+}
+
+// retry calls fn until it succeeds, returns a non transient error or
+// the attempts are exhausted, backing off between each attempt.
+func retry(ctx context.Context, attempts int, fn func() error) error {
+	backOff := holster.NewBackOff(time.Millisecond*100, time.Second*2, 2)
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= attempts || status.Code(err) != codes.Unavailable {
+			return err
+		}
+
+		select {
+		case <-time.After(backOff.Next()):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package etcdutil_test
+
+import (
+	"context"
+	"testing"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/mailgun/holster/etcdutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// faultyInvoker fails the first `failures` calls with the provided code
+func faultyInvoker(failures int, code codes.Code, calls *int) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		if *calls <= failures {
+			return status.Error(code, "injected fault")
+		}
+		return nil
+	}
+}
+
+func TestRetryUnaryInterceptor(t *testing.T) {
+	interceptor := etcdutil.RetryUnaryInterceptor(etcdutil.RetryConfig{Attempts: 3})
+
+	for _, tc := range []struct {
+		name     string
+		method   string
+		failures int
+		code     codes.Code
+		calls    int
+		err      bool
+	}{{
+		name:     "recovers before attempts exhausted",
+		method:   "/etcdserverpb.KV/Range",
+		failures: 2,
+		code:     codes.Unavailable,
+		calls:    3,
+	}, {
+		name:     "gives up after attempts exhausted",
+		method:   "/etcdserverpb.KV/Range",
+		failures: 5,
+		code:     codes.Unavailable,
+		calls:    3,
+		err:      true,
+	}, {
+		name:     "non transient errors are not retried",
+		method:   "/etcdserverpb.KV/Range",
+		failures: 1,
+		code:     codes.PermissionDenied,
+		calls:    1,
+		err:      true,
+	}, {
+		name:     "writes are not retried",
+		method:   "/etcdserverpb.KV/Put",
+		failures: 1,
+		code:     codes.Unavailable,
+		calls:    1,
+		err:      true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			err := interceptor(context.Background(), tc.method, nil, nil, nil,
+				faultyInvoker(tc.failures, tc.code, &calls))
+			assert.Equal(t, tc.calls, calls)
+			if tc.err {
+				assert.Equal(t, tc.code, status.Code(err))
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestRetryStreamInterceptor(t *testing.T) {
+	interceptor := etcdutil.RetryStreamInterceptor(etcdutil.RetryConfig{Attempts: 3})
+
+	var calls int
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+		method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		calls++
+		if calls <= 2 {
+			return nil, status.Error(codes.Unavailable, "injected fault")
+		}
+		return nil, nil
+	}
+
+	_, err := interceptor(context.Background(), &grpc.StreamDesc{}, nil,
+		"/etcdserverpb.Watch/Watch", streamer)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestNewClientWithRetryKeepsConfig(t *testing.T) {
+	cfg := &etcd.Config{
+		DialOptions: make([]grpc.DialOption, 1, 4),
+	}
+	cfg.DialOptions[0] = grpc.WithUserAgent("test")
+
+	client, err := etcdutil.NewClientWithRetry(cfg, etcdutil.RetryConfig{})
+	require.Nil(t, err)
+	defer client.Close()
+
+	// Neither the slice nor its spare capacity were written to
+	assert.Len(t, cfg.DialOptions, 1)
+	assert.Nil(t, cfg.DialOptions[:2][1])
+}
@@ -2,16 +2,28 @@ package etcdutil
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	etcd "github.com/coreos/etcd/clientv3"
 	"github.com/mailgun/holster"
+	"github.com/mailgun/holster/clock"
 	"github.com/pkg/errors"
 )
 
 const NoLease = etcd.LeaseID(-1)
 
+// The etcd client sends a keep alive every TTL/3 seconds
+const keepAlivesPerTTL = 3
+
+var (
+	// Passed to SessionConfig.OnRevoke when keep alives for the lease stopped arriving
+	ErrKeepAliveLost = errors.New("lease keep alive lost")
+	// Passed to SessionConfig.OnRevoke when the session was closed or reset
+	ErrSessionClosed = errors.New("session closed")
+)
+
 type SessionObserver func(etcd.LeaseID, error)
 
 type Session struct {
@@ -26,11 +38,52 @@ type Session struct {
 	timeout       time.Duration
 	lastKeepAlive time.Time
 	isRunning     int32
+	// Consecutive keep alive periods missed
+	failures int
+	// True if the lease should be left to expire when the session stops
+	fastClose bool
+	// The lease last reported to the observer
+	activeLease int64
+	// The TTL in seconds granted with the current lease
+	grantedTTL int64
+	// Serializes Reset(), ForceReset() and Close()
+	lifecycle sync.Mutex
+	// The lease to resume instead of granting the first lease, or NoLease
+	resumeLease etcd.LeaseID
 }
 
 type SessionConfig struct {
 	TTL      int64
 	Observer SessionObserver
+	// Optional clock used to schedule keep alive checks (Default is clock.NewRealClock())
+	Clock clock.Clock
+	// The number of consecutive keep alives, sent every TTL/3 seconds, which must be missed
+	// before the lease is considered lost, such that a single late keep alive on a congested
+	// network does not drop the lease. Above 1 a keep alive stream closed by the etcd client
+	// counts as a missed keep alive and is reopened. Values above 3 are treated as 3, since
+	// the lease has expired once a TTL passes without a keep alive. (Default is 1, where the
+	// lease is lost once a TTL passes without a keep alive or the stream is closed)
+	KeepAliveFailThreshold int
+	// If true, Close() does not revoke the lease but leaves it to expire after TTL,
+	// which avoids blocking shutdown when etcd is unreachable. Reset() always revokes.
+	FastClose bool
+	// Optional function called with the lease every time a lease is granted, after Observer
+	OnGrant func(etcd.LeaseID)
+	// Optional function called once a granted lease is lost, after Observer. The reason is
+	// ErrKeepAliveLost, ErrSessionClosed or the error which caused the loss.
+	OnRevoke func(reason error)
+	// Optional lease of a previous session which, if it has not yet expired, is kept
+	// alive by this session in place of granting a new lease. Only the first lease of
+	// the session is resumed, a new lease is granted if it is lost.
+	ResumeLease etcd.LeaseID
+	// If true, lease grants, keep alives and revokes fail fast while the etcd member we
+	// are connected to has no leader, instead of being served by a partitioned member.
+	RequireLeader bool
+	// Optional function consulted every TTL/6 seconds, while it returns true a keep alive
+	// is sent in addition to those the etcd client sends every TTL/3 seconds, such that a
+	// process busy with long running work, heavy I/O or GC pauses is less likely to miss
+	// enough keep alives to lose its lease.
+	BusyFunc func() bool
 }
 
 // NewSession creates a lease and monitors lease keep alive's for connectivity.
@@ -40,6 +93,10 @@ type SessionConfig struct {
 // is gained SessionConfig.Observer is called again with the new lease id.
 func NewSession(c *etcd.Client, conf SessionConfig) (*Session, error) {
 	holster.SetDefault(&conf.TTL, int64(30))
+	holster.SetDefault(&conf.KeepAliveFailThreshold, 1)
+	if conf.Clock == nil {
+		conf.Clock = clock.NewRealClock()
+	}
 
 	if conf.Observer == nil {
 		return nil, errors.New("provided observer function cannot be nil")
@@ -49,11 +106,21 @@ func NewSession(c *etcd.Client, conf SessionConfig) (*Session, error) {
 		return nil, errors.New("provided etcd client cannot be nil")
 	}
 
+	if conf.KeepAliveFailThreshold < 0 {
+		return nil, errors.Errorf("SessionConfig.KeepAliveFailThreshold '%d' can not be negative",
+			conf.KeepAliveFailThreshold)
+	}
+
 	s := Session{
-		timeout: time.Second * time.Duration(conf.TTL),
-		backOff: holster.NewBackOff(time.Millisecond*500, time.Duration(conf.TTL)*time.Second, 2),
-		conf:    conf,
-		client:  c,
+		timeout:     time.Second * time.Duration(conf.TTL),
+		backOff:     holster.NewBackOff(time.Millisecond*500, time.Duration(conf.TTL)*time.Second, 2),
+		conf:        conf,
+		client:      c,
+		activeLease: int64(NoLease),
+		resumeLease: NoLease,
+	}
+	if conf.ResumeLease != 0 {
+		s.resumeLease = conf.ResumeLease
 	}
 
 	s.run()
@@ -62,17 +129,28 @@ func NewSession(c *etcd.Client, conf SessionConfig) (*Session, error) {
 
 func (s *Session) run() {
 	s.ctx, s.cancel = context.WithCancel(context.Background())
-	ticker := time.NewTicker(s.timeout)
-	s.lastKeepAlive = time.Now()
+	if s.conf.RequireLeader {
+		s.ctx = etcd.WithRequireLeader(s.ctx)
+	}
+	ticker := s.conf.Clock.NewTicker(s.keepAliveInterval())
+	// Ticks only if BusyFunc is provided
+	var busyTicker clock.Ticker
+	var busy <-chan time.Time
+	if s.conf.BusyFunc != nil {
+		busyTicker = s.conf.Clock.NewTicker(s.busyInterval())
+		busy = busyTicker.C()
+	}
+	s.lastKeepAlive = s.conf.Clock.Now()
+	s.failures = 0
 	atomic.StoreInt32(&s.isRunning, 1)
 
 	s.wg.Until(func(done chan struct{}) bool {
 		// If we have lost our keep alive, attempt to regain it
 		if s.keepAlive == nil {
 			if err := s.gainLease(s.ctx); err != nil {
-				s.conf.Observer(NoLease, errors.Wrap(err, "while attempting to gain new lease"))
+				s.notify(NoLease, errors.Wrap(err, "while attempting to gain new lease"))
 				select {
-				case <-time.After(s.backOff.Next()):
+				case <-s.conf.Clock.After(s.backOff.Next()):
 					return true
 				case <-s.ctx.Done():
 					atomic.StoreInt32(&s.isRunning, 0)
@@ -81,6 +159,14 @@ func (s *Session) run() {
 				// TODO: Fix this in the library. Unreachable code
 				// return true
 			}
+			// Check keep alives against the TTL etcd granted, which may differ from the one requested
+			if granted := s.GrantedTTL(); granted != s.timeout {
+				s.timeout = granted
+				ticker.Reset(s.keepAliveInterval())
+				if busyTicker != nil {
+					busyTicker.Reset(s.busyInterval())
+				}
+			}
 		}
 		s.backOff.Reset()
 
@@ -88,23 +174,42 @@ func (s *Session) run() {
 		case _, ok := <-s.keepAlive:
 			if !ok {
 				//log.Warn("heartbeat lost")
-				s.keepAlive = nil
+				// With the default threshold of 1 this drops the keep alive straight away
+				s.missKeepAlive()
+				if s.keepAlive != nil {
+					s.reopenKeepAlive()
+				}
 			} else {
 				//log.Debug("heartbeat received")
-				s.lastKeepAlive = time.Now()
+				s.lastKeepAlive = s.conf.Clock.Now()
+				s.failures = 0
 			}
-		case <-ticker.C:
+		case <-ticker.C():
 			// Ensure we are getting heartbeats regularly
-			if time.Now().Sub(s.lastKeepAlive) > s.timeout {
+			if since := s.sinceKeepAlive(); since > s.timeout {
 				//log.Warn("too long between heartbeats")
 				s.keepAlive = nil
+				s.failures = 0
+			} else if since > s.keepAliveInterval() {
+				s.missKeepAlive()
+			}
+		case <-busy:
+			if s.conf.BusyFunc() {
+				s.keepAliveOnce()
 			}
 		case <-done:
+			ticker.Stop()
+			if busyTicker != nil {
+				busyTicker.Stop()
+			}
 			s.keepAlive = nil
-			if s.lease != nil {
+			if s.lease != nil && !s.fastClose {
 				ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+				if s.conf.RequireLeader {
+					ctx = etcd.WithRequireLeader(ctx)
+				}
 				if _, err := s.client.Revoke(ctx, s.lease.ID); err != nil {
-					s.conf.Observer(NoLease, errors.Wrap(err, "while revoking our lease during shutdown"))
+					s.notify(NoLease, errors.Wrap(err, "while revoking our lease during shutdown"))
 				}
 				cancel()
 			}
@@ -113,44 +218,203 @@ func (s *Session) run() {
 		}
 
 		if s.keepAlive == nil {
-			s.conf.Observer(NoLease, nil)
+			s.notifyReason(NoLease, nil, ErrKeepAliveLost)
 		}
 		return true
 	})
 }
 
+// keepAliveInterval returns the interval at which keep alives are checked. Without a
+// SessionConfig.KeepAliveFailThreshold the TTL is checked, otherwise each keep alive
+// the etcd client sends is checked.
+func (s *Session) keepAliveInterval() time.Duration {
+	if s.conf.KeepAliveFailThreshold <= 1 {
+		return s.timeout
+	}
+	return s.timeout / keepAlivesPerTTL
+}
+
+// missKeepAlive counts a missed keep alive and drops the keep alive once
+// SessionConfig.KeepAliveFailThreshold consecutive keep alives were missed
+func (s *Session) missKeepAlive() {
+	s.failures++
+	threshold := s.conf.KeepAliveFailThreshold
+	if threshold > keepAlivesPerTTL {
+		threshold = keepAlivesPerTTL
+	}
+	if s.failures >= threshold {
+		s.keepAlive = nil
+		s.failures = 0
+	}
+}
+
+// reopenKeepAlive asks the etcd client to resume keep alives for the current lease
+// after it closed the keep alive stream, or drops the keep alive if it can not
+func (s *Session) reopenKeepAlive() {
+	var err error
+	if s.keepAlive, err = s.client.KeepAlive(s.ctx, s.lease.ID); err != nil {
+		s.keepAlive = nil
+		s.failures = 0
+	}
+}
+
+// busyInterval returns the interval at which BusyFunc is consulted, which is twice
+// as often as the etcd client sends keep alives
+func (s *Session) busyInterval() time.Duration {
+	return s.timeout / 6
+}
+
+// keepAliveOnce sends a single keep alive for the current lease in addition to those
+// sent by the etcd client
+func (s *Session) keepAliveOnce() {
+	ctx, cancel := context.WithTimeout(s.ctx, s.busyInterval())
+	defer cancel()
+
+	if _, err := s.client.KeepAliveOnce(ctx, s.lease.ID); err != nil {
+		// The keep alive checks decide whether the lease is lost
+		return
+	}
+	s.lastKeepAlive = s.conf.Clock.Now()
+	s.failures = 0
+}
+
+// sinceKeepAlive returns the time elapsed since the last keep alive. Times returned
+// by the real clock carry a monotonic reading so NTP steps do not affect the result.
+// A clock without one may appear to go backwards, in which case the last keep alive
+// is re-anchored to now rather than dropping a healthy lease.
+func (s *Session) sinceKeepAlive() time.Duration {
+	now := s.conf.Clock.Now()
+	elapsed := now.Sub(s.lastKeepAlive)
+	if elapsed < 0 {
+		s.lastKeepAlive = now
+		return 0
+	}
+	return elapsed
+}
+
+// resume returns the lease provided by SessionConfig.ResumeLease on the first call if it has
+// not yet expired, otherwise it returns nil
+func (s *Session) resume(ctx context.Context) (*etcd.LeaseGrantResponse, error) {
+	if s.resumeLease == NoLease {
+		return nil, nil
+	}
+
+	resp, err := s.client.TimeToLive(ctx, s.resumeLease)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while resuming lease '%x'", s.resumeLease)
+	}
+	leaseID := s.resumeLease
+	s.resumeLease = NoLease
+
+	// The lease has expired or was revoked
+	if resp.TTL <= 0 {
+		return nil, nil
+	}
+	return &etcd.LeaseGrantResponse{ID: leaseID, TTL: resp.GrantedTTL}, nil
+}
+
+// Reset revokes the current lease and acquires a new one, see ForceReset()
 func (s *Session) Reset() {
+	s.lifecycle.Lock()
+	defer s.lifecycle.Unlock()
+
 	if atomic.LoadInt32(&s.isRunning) != 1 {
 		return
 	}
-	s.Close()
+	s.close(false)
 	s.run()
 }
 
+// ForceReset revokes the current lease and acquires a new one while keeping the session
+// alive, such that a supervisor can deliberately cycle the lease after detecting a problem
+// with whatever the lease protects. SessionConfig.Observer is called with NoLease and then
+// with the new lease once granted, so an Election using the session campaigns again.
+// It is safe to call concurrently with keep alives and other calls to ForceReset(),
+// and does nothing once the session is closed.
+func (s *Session) ForceReset() {
+	s.Reset()
+}
+
 // Close terminates the session shutting down all network operations,
 // then SessionConfig.Observer is called with -1 (NoLease), only returns
 // once the session has closed successfully.
 func (s *Session) Close() {
+	s.lifecycle.Lock()
+	defer s.lifecycle.Unlock()
+	s.close(s.conf.FastClose)
+}
+
+func (s *Session) close(fastClose bool) {
 	if atomic.LoadInt32(&s.isRunning) != 1 {
 		return
 	}
 
+	s.fastClose = fastClose
 	s.cancel()
 	s.wg.Stop()
-	s.conf.Observer(NoLease, nil)
+	s.notifyReason(NoLease, nil, ErrSessionClosed)
+}
+
+// notify records the lease as active, or inactive if NoLease, then notifies the observer
+func (s *Session) notify(leaseID etcd.LeaseID, err error) {
+	s.notifyReason(leaseID, err, err)
+}
+
+// notifyReason is like notify but calls OnRevoke with `reason` if an active lease was lost
+func (s *Session) notifyReason(leaseID etcd.LeaseID, err, reason error) {
+	prev := etcd.LeaseID(atomic.SwapInt64(&s.activeLease, int64(leaseID)))
+	s.conf.Observer(leaseID, err)
+
+	if leaseID != NoLease && s.conf.OnGrant != nil {
+		s.conf.OnGrant(leaseID)
+	}
+	if leaseID == NoLease && prev != NoLease && s.conf.OnRevoke != nil {
+		s.conf.OnRevoke(reason)
+	}
+}
+
+// Put writes a key bound to the current lease of the session, such that the key
+// is removed by etcd if the session loses connectivity or is closed. It returns
+// an error if the session currently has no lease.
+func (s *Session) Put(ctx context.Context, key, val string) error {
+	leaseID := etcd.LeaseID(atomic.LoadInt64(&s.activeLease))
+	if leaseID == NoLease {
+		return errors.Errorf("while putting '%s': session has no active lease", key)
+	}
+
+	if _, err := s.client.Put(ctx, key, val, etcd.WithLease(leaseID)); err != nil {
+		return errors.Wrapf(err, "while putting '%s'", key)
+	}
+	return nil
+}
+
+// GrantedTTL returns the TTL etcd granted the most recent lease, which may differ from
+// SessionConfig.TTL, for instance if etcd enforces a minimum TTL. The value is updated
+// before SessionConfig.Observer is notified of a new lease. Returns zero if the session
+// has not been granted a lease yet.
+func (s *Session) GrantedTTL() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.grantedTTL)) * time.Second
 }
 
 func (s *Session) gainLease(ctx context.Context) error {
 	var err error
-	s.lease, err = s.client.Grant(ctx, s.conf.TTL)
-	if err != nil {
-		return errors.Wrapf(err, "during grant lease")
+	if s.lease, err = s.resume(ctx); err != nil {
+		return err
+	}
+	if s.lease == nil {
+		if s.lease, err = s.client.Grant(ctx, s.conf.TTL); err != nil {
+			return errors.Wrapf(err, "during grant lease")
+		}
 	}
 
+	atomic.StoreInt64(&s.grantedTTL, s.lease.TTL)
+
 	s.keepAlive, err = s.client.KeepAlive(s.ctx, s.lease.ID)
 	if err != nil {
 		return err
 	}
-	s.conf.Observer(s.lease.ID, nil)
+	s.lastKeepAlive = s.conf.Clock.Now()
+	s.failures = 0
+	s.notify(s.lease.ID, nil)
 	return nil
 }
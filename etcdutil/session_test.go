@@ -1,17 +1,24 @@
 package etcdutil_test
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Shopify/toxiproxy"
 	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"github.com/mailgun/holster/clock"
 	"github.com/mailgun/holster/etcdutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 var proxy *toxiproxy.Proxy
@@ -118,3 +125,435 @@ func TestConnectivityLost(t *testing.T) {
 	// Should get a final NoLease after close
 	assert.Equal(t, etcdutil.NoLease, getLease())
 }
+
+// skewedClock is a frozen clock whose wall time can jump independently of its timers
+type skewedClock struct {
+	*clock.FrozenClock
+	skew int64
+}
+
+func (c *skewedClock) Now() time.Time {
+	return c.FrozenClock.Now().Add(time.Duration(atomic.LoadInt64(&c.skew)))
+}
+
+func TestSessionClockJumpBackwards(t *testing.T) {
+	leaseChan := make(chan etcd.LeaseID, 5)
+	clk := &skewedClock{FrozenClock: clock.NewFrozenClock(time.Now())}
+
+	session, err := etcdutil.NewSession(client, etcdutil.SessionConfig{
+		Observer: func(leaseId etcd.LeaseID, err error) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			leaseChan <- leaseId
+		},
+		Clock: clk,
+		TTL:   1,
+	})
+	require.Nil(t, err)
+	defer session.Close()
+
+	select {
+	case id := <-leaseChan:
+		assert.NotEqual(t, etcdutil.NoLease, id)
+	case <-time.After(time.Second * 5):
+		require.FailNow(t, "Timeout waiting for lease id")
+	}
+
+	// Step the wall clock back an hour, then fire the keep alive check
+	atomic.StoreInt64(&clk.skew, int64(-time.Hour))
+	clk.Advance(time.Second)
+
+	select {
+	case id := <-leaseChan:
+		t.Fatalf("lease dropped after wall clock jump: %v", id)
+	case <-time.After(time.Millisecond * 500):
+	}
+}
+
+func TestSessionKeepAliveFailThreshold(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		threshold int
+		// The number of keep alive checks which pass before the lease is dropped
+		checks int
+		// The time between keep alive checks
+		interval time.Duration
+	}{{
+		name:      "default checks the TTL",
+		threshold: 1,
+		checks:    1,
+		interval:  time.Second * 30,
+	}, {
+		name:      "below the TTL",
+		threshold: 2,
+		checks:    2,
+		interval:  time.Second * 10,
+	}, {
+		name:      "equal to the TTL",
+		threshold: 3,
+		checks:    3,
+		interval:  time.Second * 10,
+	}, {
+		name:      "capped at the TTL",
+		threshold: 10,
+		checks:    3,
+		interval:  time.Second * 10,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			leaseChan := make(chan etcd.LeaseID, 5)
+			clk := clock.NewFrozenClock(time.Now())
+
+			session, err := etcdutil.NewSession(client, etcdutil.SessionConfig{
+				Observer: func(leaseId etcd.LeaseID, err error) {
+					if err != nil {
+						t.Fatal(err)
+					}
+					leaseChan <- leaseId
+				},
+				KeepAliveFailThreshold: tc.threshold,
+				Clock:                  clk,
+				// Real keep alives are sent every TTL/3, so none arrive during the test
+				TTL: 30,
+			})
+			require.Nil(t, err)
+			defer session.Close()
+
+			select {
+			case id := <-leaseChan:
+				assert.NotEqual(t, etcdutil.NoLease, id)
+			case <-time.After(time.Second * 5):
+				require.FailNow(t, "Timeout waiting for lease id")
+			}
+
+			// Each check misses a keep alive, the lease is kept below the threshold
+			clk.Advance(time.Second)
+			for i := 1; i < tc.checks; i++ {
+				clk.Advance(tc.interval)
+
+				select {
+				case id := <-leaseChan:
+					t.Fatalf("lease dropped after %d missed keep alives: %v", i, id)
+				case <-time.After(time.Millisecond * 500):
+				}
+			}
+
+			// Missing one more keep alive reaches the threshold
+			clk.Advance(tc.interval)
+
+			select {
+			case id := <-leaseChan:
+				assert.Equal(t, etcdutil.NoLease, id)
+			case <-time.After(time.Second * 5):
+				require.FailNow(t, "Timeout waiting for lease to be dropped")
+			}
+		})
+	}
+}
+
+func TestSessionNegativeKeepAliveFailThreshold(t *testing.T) {
+	_, err := etcdutil.NewSession(client, etcdutil.SessionConfig{
+		Observer:               func(etcd.LeaseID, error) {},
+		KeepAliveFailThreshold: -1,
+	})
+	require.NotNil(t, err)
+	assert.Equal(t, "SessionConfig.KeepAliveFailThreshold '-1' can not be negative", err.Error())
+}
+
+func TestSessionPut(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	leaseChan := make(chan etcd.LeaseID, 5)
+	session, err := etcdutil.NewSession(client, etcdutil.SessionConfig{
+		Observer: func(leaseId etcd.LeaseID, err error) {
+			leaseChan <- leaseId
+		},
+	})
+	require.Nil(t, err)
+	defer session.Close()
+
+	var leaseID etcd.LeaseID
+	select {
+	case leaseID = <-leaseChan:
+		require.NotEqual(t, etcdutil.NoLease, leaseID)
+	case <-ctx.Done():
+		require.FailNow(t, "Timeout waiting for lease id")
+	}
+
+	require.Nil(t, session.Put(ctx, "/services/my-service", "10.0.0.1"))
+
+	resp, err := client.Get(ctx, "/services/my-service")
+	require.Nil(t, err)
+	require.Equal(t, int64(1), resp.Count)
+	assert.Equal(t, "10.0.0.1", string(resp.Kvs[0].Value))
+	assert.Equal(t, int64(leaseID), resp.Kvs[0].Lease)
+
+	// Killing the lease removes the key
+	_, err = client.Revoke(ctx, leaseID)
+	require.Nil(t, err)
+
+	resp, err = client.Get(ctx, "/services/my-service")
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), resp.Count)
+}
+
+func TestSessionPutNoLease(t *testing.T) {
+	session, err := etcdutil.NewSession(client, etcdutil.SessionConfig{
+		Observer: func(etcd.LeaseID, error) {},
+	})
+	require.Nil(t, err)
+	session.Close()
+
+	err = session.Put(context.Background(), "/services/my-service", "10.0.0.1")
+	require.NotNil(t, err)
+	assert.Equal(t, "while putting '/services/my-service': session has no active lease", err.Error())
+}
+
+func TestSessionGrantedTTL(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	leaseChan := make(chan etcd.LeaseID, 5)
+	session, err := etcdutil.NewSession(client, etcdutil.SessionConfig{
+		Observer: func(leaseId etcd.LeaseID, err error) {
+			leaseChan <- leaseId
+		},
+		// Below the minimum TTL etcd enforces by default
+		TTL: 1,
+	})
+	require.Nil(t, err)
+	defer session.Close()
+
+	var leaseID etcd.LeaseID
+	select {
+	case leaseID = <-leaseChan:
+		require.NotEqual(t, etcdutil.NoLease, leaseID)
+	case <-ctx.Done():
+		require.FailNow(t, "Timeout waiting for lease id")
+	}
+
+	resp, err := client.TimeToLive(ctx, leaseID)
+	require.Nil(t, err)
+	assert.Equal(t, time.Duration(resp.GrantedTTL)*time.Second, session.GrantedTTL())
+	assert.True(t, session.GrantedTTL() >= time.Second)
+}
+
+func TestSessionOnGrantOnRevoke(t *testing.T) {
+	granted := make(chan etcd.LeaseID, 5)
+	revoked := make(chan error, 5)
+
+	session, err := etcdutil.NewSession(client, etcdutil.SessionConfig{
+		Observer: func(etcd.LeaseID, error) {},
+		OnGrant: func(leaseID etcd.LeaseID) {
+			granted <- leaseID
+		},
+		OnRevoke: func(reason error) {
+			revoked <- reason
+		},
+		TTL: 1,
+	})
+	require.Nil(t, err)
+	defer session.Close()
+
+	select {
+	case leaseID := <-granted:
+		assert.NotEqual(t, etcdutil.NoLease, leaseID)
+	case <-time.After(time.Second * 5):
+		require.FailNow(t, "Timeout waiting for OnGrant")
+	}
+
+	// Interrupt the connection
+	proxy.Stop()
+
+	select {
+	case reason := <-revoked:
+		assert.Equal(t, etcdutil.ErrKeepAliveLost, reason)
+	case <-time.After(time.Second * 5):
+		require.FailNow(t, "Timeout waiting for OnRevoke")
+	}
+	require.Nil(t, proxy.Start())
+
+	// A new lease is granted once the connection is restored
+	select {
+	case <-granted:
+	case <-time.After(time.Second * 10):
+		require.FailNow(t, "Timeout waiting for OnGrant")
+	}
+
+	session.Close()
+	select {
+	case reason := <-revoked:
+		assert.Equal(t, etcdutil.ErrSessionClosed, reason)
+	case <-time.After(time.Second * 5):
+		require.FailNow(t, "Timeout waiting for OnRevoke")
+	}
+	assert.Len(t, revoked, 0)
+}
+
+func TestSessionForceReset(t *testing.T) {
+	leaseChan := make(chan etcd.LeaseID, 20)
+	getLease := func() etcd.LeaseID {
+		select {
+		case id := <-leaseChan:
+			return id
+		case <-time.After(time.Second * 5):
+			require.FailNow(t, "Timeout waiting for lease id")
+		}
+		return 0
+	}
+
+	session, err := etcdutil.NewSession(client, etcdutil.SessionConfig{
+		Observer: func(leaseID etcd.LeaseID, err error) {
+			require.Nil(t, err)
+			leaseChan <- leaseID
+		},
+	})
+	require.Nil(t, err)
+	defer session.Close()
+
+	first := getLease()
+	assert.NotEqual(t, etcdutil.NoLease, first)
+
+	session.ForceReset()
+	assert.Equal(t, etcdutil.NoLease, getLease())
+	second := getLease()
+	assert.NotEqual(t, etcdutil.NoLease, second)
+	assert.NotEqual(t, first, second)
+
+	// The previous lease was revoked
+	resp, err := client.TimeToLive(context.Background(), first)
+	require.Nil(t, err)
+	assert.Equal(t, int64(-1), resp.TTL)
+
+	// Run with -race to detect unsynchronized access
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session.ForceReset()
+		}()
+	}
+	wg.Wait()
+
+	// The session remains alive with a lease
+	for {
+		if id := getLease(); id != etcdutil.NoLease && len(leaseChan) == 0 {
+			break
+		}
+	}
+}
+
+func TestSessionRequireLeader(t *testing.T) {
+	// Record which lease RPCs require the etcd member to have a leader
+	var mutex sync.Mutex
+	requireLeader := make(map[string]bool)
+	c, err := etcdutil.NewClient(&etcd.Config{
+		DialOptions: []grpc.DialOption{grpc.WithUnaryInterceptor(
+			func(ctx context.Context, method string, req, reply interface{},
+				cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				mutex.Lock()
+				requireLeader[method] = len(md.Get(rpctypes.MetadataRequireLeaderKey)) != 0
+				mutex.Unlock()
+				return invoker(ctx, method, req, reply, cc, opts...)
+			})},
+	})
+	require.Nil(t, err)
+	defer c.Close()
+
+	leaseChan := make(chan etcd.LeaseID, 5)
+	session, err := etcdutil.NewSession(c, etcdutil.SessionConfig{
+		Observer: func(leaseID etcd.LeaseID, err error) {
+			leaseChan <- leaseID
+		},
+		RequireLeader: true,
+	})
+	require.Nil(t, err)
+
+	select {
+	case leaseID := <-leaseChan:
+		assert.NotEqual(t, etcdutil.NoLease, leaseID)
+	case <-time.After(time.Second * 5):
+		require.FailNow(t, "Timeout waiting for lease id")
+	}
+	session.Close()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.True(t, requireLeader["/etcdserverpb.Lease/LeaseGrant"])
+	assert.True(t, requireLeader["/etcdserverpb.Lease/LeaseRevoke"])
+}
+
+func TestSessionBusyFunc(t *testing.T) {
+	// Count the keep alive streams, the etcd client keeps a single stream open for
+	// its own keep alives while each additional keep alive opens a stream of its own
+	var keepAlives int32
+	c, err := etcdutil.NewClient(&etcd.Config{
+		DialOptions: []grpc.DialOption{grpc.WithStreamInterceptor(
+			func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+				streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				if method == "/etcdserverpb.Lease/LeaseKeepAlive" {
+					atomic.AddInt32(&keepAlives, 1)
+				}
+				return streamer(ctx, desc, cc, method, opts...)
+			})},
+	})
+	require.Nil(t, err)
+	defer c.Close()
+
+	var busy int32
+	leaseChan := make(chan etcd.LeaseID, 5)
+	clk := clock.NewFrozenClock(time.Now())
+	session, err := etcdutil.NewSession(c, etcdutil.SessionConfig{
+		Observer: func(leaseID etcd.LeaseID, err error) {
+			leaseChan <- leaseID
+		},
+		BusyFunc: func() bool {
+			return atomic.LoadInt32(&busy) == 1
+		},
+		Clock: clk,
+		// Real keep alives are sent every TTL/3 and BusyFunc is consulted every TTL/6
+		TTL: 30,
+	})
+	require.Nil(t, err)
+	defer session.Close()
+
+	select {
+	case leaseID := <-leaseChan:
+		assert.NotEqual(t, etcdutil.NoLease, leaseID)
+	case <-time.After(time.Second * 5):
+		require.FailNow(t, "Timeout waiting for lease id")
+	}
+	require.True(t, clk.Wait4Scheduled(2, time.Second))
+	time.Sleep(time.Millisecond * 200)
+	before := atomic.LoadInt32(&keepAlives)
+
+	// No additional keep alives are sent while not busy
+	clk.Advance(time.Second * 5)
+	clk.Advance(time.Second * 5)
+	time.Sleep(time.Millisecond * 200)
+	assert.Equal(t, before, atomic.LoadInt32(&keepAlives))
+
+	// A keep alive is sent every TTL/6 while busy
+	atomic.StoreInt32(&busy, 1)
+	for i := int32(1); i <= 3; i++ {
+		clk.Advance(time.Second * 5)
+		deadline := time.Now().Add(time.Second * 5)
+		for atomic.LoadInt32(&keepAlives) < before+i {
+			if time.Now().After(deadline) {
+				require.FailNow(t, "Timeout waiting for keep alive")
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+	assert.Equal(t, before+3, atomic.LoadInt32(&keepAlives))
+
+	// The lease was kept throughout
+	select {
+	case leaseID := <-leaseChan:
+		require.FailNow(t, "lease changed", "lease %x", leaseID)
+	default:
+	}
+}
@@ -0,0 +1,56 @@
+package etcdutil
+
+import "context"
+
+// Tracer starts spans around election campaign operations. It is intentionally
+// small so holster does not depend on a tracing library, an OpenTelemetry
+// TracerProvider can be adapted in a few lines:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, etcdutil.Span) {
+//		ctx, span := t.tracer.Start(ctx, name)
+//		return ctx, otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) AddEvent(name string, attrs map[string]string) {
+//		var kvs []attribute.KeyValue
+//		for k, v := range attrs {
+//			kvs = append(kvs, attribute.String(k, v))
+//		}
+//		s.Span.AddEvent(name, trace.WithAttributes(kvs...))
+//	}
+//
+//	func (s otelSpan) RecordError(err error) {
+//		s.Span.RecordError(err)
+//		s.Span.SetStatus(codes.Error, err.Error())
+//	}
+//
+//	func (s otelSpan) End() { s.Span.End() }
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation started by a Tracer
+type Span interface {
+	// AddEvent records a named event, such as a leadership transition, on the span
+	AddEvent(name string, attrs map[string]string)
+	// RecordError marks the span as failed with the provided error
+	RecordError(err error)
+	// End completes the span
+	End()
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) AddEvent(string, map[string]string) {}
+func (noopSpan) RecordError(error)                  {}
+func (noopSpan) End()                               {}
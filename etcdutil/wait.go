@@ -0,0 +1,44 @@
+package etcdutil
+
+import (
+	"context"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/pkg/errors"
+)
+
+// WaitForValue blocks until `key` holds `value` or the context is cancelled. It returns
+// immediately if the key already holds the value, otherwise it watches the key for
+// changes made after the initial read.
+//
+//	// Wait for the migration to complete before starting
+//	err := etcdutil.WaitForValue(ctx, client, "/my-service/migration", "done")
+func WaitForValue(ctx context.Context, client *etcd.Client, key, value string) error {
+	resp, err := client.Get(ctx, key)
+	if err != nil {
+		return errors.Wrapf(err, "while getting '%s'", key)
+	}
+	if len(resp.Kvs) != 0 && string(resp.Kvs[0].Value) == value {
+		return nil
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	watchChan := client.Watch(etcd.WithRequireLeader(watchCtx), key, etcd.WithRev(resp.Header.Revision+1))
+	for resp := range watchChan {
+		if err := resp.Err(); err != nil {
+			return errors.Wrapf(err, "while watching '%s'", key)
+		}
+		for _, event := range resp.Events {
+			if event.Type == etcd.EventTypePut && string(event.Kv.Value) == value {
+				return nil
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return errors.Errorf("watch on '%s' closed unexpectedly", key)
+}
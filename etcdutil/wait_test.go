@@ -0,0 +1,40 @@
+package etcdutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mailgun/holster/etcdutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForValue(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	_, err := client.Put(ctx, "/wait-for-value", "pending")
+	require.Nil(t, err)
+
+	go func() {
+		time.Sleep(time.Millisecond * 500)
+		client.Put(ctx, "/wait-for-value", "other")
+		client.Put(ctx, "/wait-for-value", "done")
+	}()
+
+	start := time.Now()
+	require.Nil(t, etcdutil.WaitForValue(ctx, client, "/wait-for-value", "done"))
+	assert.True(t, time.Since(start) >= time.Millisecond*500)
+
+	// Returns immediately if the key already holds the value
+	require.Nil(t, etcdutil.WaitForValue(ctx, client, "/wait-for-value", "done"))
+}
+
+func TestWaitForValueCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+	defer cancel()
+
+	err := etcdutil.WaitForValue(ctx, client, "/wait-for-value-cancelled", "never")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
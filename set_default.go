@@ -16,7 +16,13 @@ limitations under the License.
 package holster
 
 import (
+	"fmt"
+	"os"
 	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 // If 'dest' is empty or of zero value, assign the default value.
@@ -31,7 +37,8 @@ import (
 // 	holster.SetDefault(&config.Bar, 200)
 //
 // Supply additional default values and SetDefault will
-// choose the first default that is not of zero value
+// choose the first default that is not of zero value, nil
+// defaults are skipped. If all defaults are zero 'dest' remains unchanged.
 //  holster.SetDefault(&config.Foo, os.Getenv("FOO"), "default")
 func SetDefault(dest interface{}, defaultValue ...interface{}) {
 	d := reflect.ValueOf(dest)
@@ -44,13 +51,86 @@ func SetDefault(dest interface{}, defaultValue ...interface{}) {
 		for _, value := range defaultValue {
 			v := reflect.ValueOf(value)
 			if !IsZeroValue(v) {
-				d.Set(reflect.ValueOf(value))
+				assign("holster.SetDefault", d, v)
 				return
 			}
 		}
 	}
 }
 
+// assign sets 'd' to 'v', panicking with a message naming the caller if the types do not match
+func assign(caller string, d, v reflect.Value) {
+	if !v.Type().AssignableTo(d.Type()) {
+		panic(fmt.Sprintf("%s: value of type %s is not assignable to type %s", caller, v.Type(), d.Type()))
+	}
+	d.Set(v)
+}
+
+// If 'dest' is empty or of zero value, assign the value of the environment
+// variable 'envKey' converted to the type of 'dest', or the default value if
+// the environment variable is not set. Supported types are string, bool,
+// time.Duration and the signed and unsigned integer types. Returns an error if
+// the environment variable can not be converted.
+//
+// This panics if the value is not a pointer or if value and default value
+// are not of the same type.
+//
+//	var config struct {
+//		Timeout time.Duration
+//	}
+//	err := holster.SetDefaultFromEnv(&config.Timeout, "TIMEOUT", time.Second)
+func SetDefaultFromEnv(dest interface{}, envKey string, defaultValue interface{}) error {
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Ptr {
+		panic("holster.SetDefaultFromEnv: Expected first argument to be of type reflect.Ptr")
+	}
+	d = reflect.Indirect(d)
+	if !IsZeroValue(d) {
+		return nil
+	}
+
+	env := os.Getenv(envKey)
+	if env == "" {
+		SetDefault(dest, defaultValue)
+		return nil
+	}
+
+	if d.Type() == reflect.TypeOf(time.Duration(0)) {
+		v, err := time.ParseDuration(env)
+		if err != nil {
+			return errors.Errorf("%s='%s' is not a duration", envKey, env)
+		}
+		d.SetInt(int64(v))
+		return nil
+	}
+
+	switch d.Kind() {
+	case reflect.String:
+		d.SetString(env)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(env)
+		if err != nil {
+			return errors.Errorf("%s='%s' is not a bool", envKey, env)
+		}
+		d.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(env, 10, d.Type().Bits())
+		if err != nil {
+			return errors.Errorf("%s='%s' is not an %s", envKey, env, d.Type())
+		}
+		d.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(env, 10, d.Type().Bits())
+		if err != nil {
+			return errors.Errorf("%s='%s' is not an %s", envKey, env, d.Type())
+		}
+		d.SetUint(v)
+	default:
+		return errors.Errorf("holster.SetDefaultFromEnv: unsupported type %s", d.Type())
+	}
+	return nil
+}
+
 // Assign the first value that is not empty or of zero value.
 // This panics if the value is not a pointer or if value and
 // default value are not of the same type.
@@ -80,7 +160,7 @@ func SetOverride(dest interface{}, values ...interface{}) {
 	for _, value := range values {
 		v := reflect.ValueOf(value)
 		if !IsZeroValue(v) {
-			d.Set(reflect.ValueOf(value))
+			assign("holster.SetOverride", d, v)
 			return
 		}
 	}
@@ -98,6 +178,8 @@ func IsZero(value interface{}) bool {
 // 	holster.IsZeroValue(reflect.ValueOf(count)) == true
 func IsZeroValue(value reflect.Value) bool {
 	switch value.Kind() {
+	case reflect.Invalid:
+		return true
 	case reflect.Array, reflect.String:
 		return value.Len() == 0
 	case reflect.Bool:
@@ -0,0 +1,52 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+Copyright 2017 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package holster
+
+// Default assigns `def` to `dest` if `dest` is of zero value. Unlike
+// SetDefault() the types are checked at compile time and no reflection is used.
+//
+//	var config struct {
+//		Foo string
+//		Bar int
+//	}
+//	holster.Default(&config.Foo, "default")
+//	holster.Default(&config.Bar, 200)
+func Default[T comparable](dest *T, def T) {
+	var zero T
+	if *dest == zero {
+		*dest = def
+	}
+}
+
+// Defaults assigns the first default that is not of zero value to `dest` if
+// `dest` is of zero value.
+//
+//	holster.Defaults(&config.Foo, os.Getenv("FOO"), "default")
+func Defaults[T comparable](dest *T, defs ...T) {
+	var zero T
+	if *dest != zero {
+		return
+	}
+	for _, def := range defs {
+		if def != zero {
+			*dest = def
+			return
+		}
+	}
+}
@@ -0,0 +1,87 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+Copyright 2017 Mailgun Technologies Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package holster_test
+
+import (
+	"time"
+
+	"github.com/mailgun/holster"
+	. "gopkg.in/check.v1"
+)
+
+type priority int
+
+type endpoint struct {
+	Host string
+	Port int
+}
+
+func (s *SetDefaultTestSuite) TestDefault(c *C) {
+	var conf struct {
+		Foo      string
+		Bar      int
+		Timeout  time.Duration
+		Priority priority
+		Endpoint endpoint
+	}
+
+	// Should apply the default values
+	holster.Default(&conf.Foo, "default")
+	holster.Default(&conf.Bar, 200)
+	holster.Default(&conf.Timeout, time.Second)
+	holster.Default(&conf.Priority, priority(5))
+	holster.Default(&conf.Endpoint, endpoint{Host: "localhost", Port: 80})
+
+	c.Assert(conf.Foo, Equals, "default")
+	c.Assert(conf.Bar, Equals, 200)
+	c.Assert(conf.Timeout, Equals, time.Second)
+	c.Assert(conf.Priority, Equals, priority(5))
+	c.Assert(conf.Endpoint, Equals, endpoint{Host: "localhost", Port: 80})
+
+	conf.Foo = "thrawn"
+	conf.Bar = 500
+	conf.Priority = 1
+	conf.Endpoint = endpoint{Host: "example.com"}
+
+	// Should NOT apply the default values
+	holster.Default(&conf.Foo, "default")
+	holster.Default(&conf.Bar, 200)
+	holster.Default(&conf.Priority, priority(5))
+	holster.Default(&conf.Endpoint, endpoint{Host: "localhost", Port: 80})
+
+	c.Assert(conf.Foo, Equals, "thrawn")
+	c.Assert(conf.Bar, Equals, 500)
+	c.Assert(conf.Priority, Equals, priority(1))
+	c.Assert(conf.Endpoint, Equals, endpoint{Host: "example.com"})
+}
+
+func (s *SetDefaultTestSuite) TestDefaults(c *C) {
+	var foo string
+	holster.Defaults(&foo, "", "first", "second")
+	c.Assert(foo, Equals, "first")
+
+	holster.Defaults(&foo, "other")
+	c.Assert(foo, Equals, "first")
+
+	var bar int
+	holster.Defaults(&bar, 0, 0)
+	c.Assert(bar, Equals, 0)
+	holster.Defaults(&bar)
+	c.Assert(bar, Equals, 0)
+}
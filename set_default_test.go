@@ -16,6 +16,9 @@ limitations under the License.
 package holster_test
 
 import (
+	"os"
+	"time"
+
 	"github.com/mailgun/holster"
 	. "gopkg.in/check.v1"
 )
@@ -72,6 +75,39 @@ func (s *SetDefaultTestSuite) TestIfDefaultPrecedence(c *C) {
 	c.Assert(conf.Bar, Equals, "bar")
 }
 
+func (s *SetDefaultTestSuite) TestCascade(c *C) {
+	var conf struct {
+		Foo string
+		Bar int
+		Baz string
+	}
+
+	// The first non zero candidate wins
+	holster.SetDefault(&conf.Foo, "", nil, "first", "second")
+	c.Assert(conf.Foo, Equals, "first")
+
+	holster.SetDefault(&conf.Bar, 0, 0, 3, 4)
+	c.Assert(conf.Bar, Equals, 3)
+
+	// All zero candidates leave the value unchanged
+	holster.SetDefault(&conf.Baz, "", nil, "")
+	c.Assert(conf.Baz, Equals, "")
+	holster.SetDefault(&conf.Baz)
+	c.Assert(conf.Baz, Equals, "")
+}
+
+func (s *SetDefaultTestSuite) TestCascadeTypePanic(c *C) {
+	defer func() {
+		r := recover()
+		c.Assert(r, Equals, "holster.SetDefault: value of type int is not assignable to type string")
+	}()
+
+	// Zero candidates are skipped before the mismatched one is reached
+	var thing string
+	holster.SetDefault(&thing, "", 0, 1, "thrawn")
+	c.Fatalf("Should have caught panic")
+}
+
 func (s *SetDefaultTestSuite) TestIsEmpty(c *C) {
 	var count64 int64
 	var thing string
@@ -89,7 +125,7 @@ func (s *SetDefaultTestSuite) TestIsEmpty(c *C) {
 func (s *SetDefaultTestSuite) TestIfEmptyTypePanic(c *C) {
 	defer func() {
 		if r := recover(); r != nil {
-			c.Assert(r, Equals, "reflect.Set: value of type int is not assignable to type string")
+			c.Assert(r, Equals, "holster.SetDefault: value of type int is not assignable to type string")
 		}
 	}()
 
@@ -111,3 +147,83 @@ func (s *SetDefaultTestSuite) TestIfEmptyNonPtrPanic(c *C) {
 	holster.SetDefault(thing, "thrawn")
 	c.Fatalf("Should have caught panic")
 }
+
+func (s *SetDefaultTestSuite) TestFromEnv(c *C) {
+	for k, v := range map[string]string{
+		"HOLSTER_STRING":   "from-env",
+		"HOLSTER_BOOL":     "true",
+		"HOLSTER_INT":      "-42",
+		"HOLSTER_UINT":     "42",
+		"HOLSTER_DURATION": "15s",
+	} {
+		c.Assert(os.Setenv(k, v), IsNil)
+		defer os.Unsetenv(k)
+	}
+
+	var conf struct {
+		String   string
+		Bool     bool
+		Int      int32
+		Uint     uint
+		Duration time.Duration
+	}
+	c.Assert(holster.SetDefaultFromEnv(&conf.String, "HOLSTER_STRING", "default"), IsNil)
+	c.Assert(holster.SetDefaultFromEnv(&conf.Bool, "HOLSTER_BOOL", false), IsNil)
+	c.Assert(holster.SetDefaultFromEnv(&conf.Int, "HOLSTER_INT", int32(1)), IsNil)
+	c.Assert(holster.SetDefaultFromEnv(&conf.Uint, "HOLSTER_UINT", uint(1)), IsNil)
+	c.Assert(holster.SetDefaultFromEnv(&conf.Duration, "HOLSTER_DURATION", time.Second), IsNil)
+
+	c.Assert(conf.String, Equals, "from-env")
+	c.Assert(conf.Bool, Equals, true)
+	c.Assert(conf.Int, Equals, int32(-42))
+	c.Assert(conf.Uint, Equals, uint(42))
+	c.Assert(conf.Duration, Equals, time.Second*15)
+}
+
+func (s *SetDefaultTestSuite) TestFromEnvPrecedence(c *C) {
+	c.Assert(os.Setenv("HOLSTER_STRING", "from-env"), IsNil)
+	defer os.Unsetenv("HOLSTER_STRING")
+
+	// The field wins over the environment
+	foo := "field"
+	c.Assert(holster.SetDefaultFromEnv(&foo, "HOLSTER_STRING", "default"), IsNil)
+	c.Assert(foo, Equals, "field")
+
+	// The environment wins over the default
+	foo = ""
+	c.Assert(holster.SetDefaultFromEnv(&foo, "HOLSTER_STRING", "default"), IsNil)
+	c.Assert(foo, Equals, "from-env")
+
+	// The default is used when the environment is not set
+	foo = ""
+	c.Assert(holster.SetDefaultFromEnv(&foo, "HOLSTER_NOT_SET", "default"), IsNil)
+	c.Assert(foo, Equals, "default")
+}
+
+func (s *SetDefaultTestSuite) TestFromEnvError(c *C) {
+	for k, v := range map[string]string{
+		"HOLSTER_BOOL":     "maybe",
+		"HOLSTER_INT":      "foo",
+		"HOLSTER_DURATION": "15",
+	} {
+		c.Assert(os.Setenv(k, v), IsNil)
+		defer os.Unsetenv(k)
+	}
+
+	var b bool
+	err := holster.SetDefaultFromEnv(&b, "HOLSTER_BOOL", true)
+	c.Assert(err, ErrorMatches, "HOLSTER_BOOL='maybe' is not a bool")
+
+	var i int8
+	err = holster.SetDefaultFromEnv(&i, "HOLSTER_INT", int8(1))
+	c.Assert(err, ErrorMatches, "HOLSTER_INT='foo' is not an int8")
+
+	var d time.Duration
+	err = holster.SetDefaultFromEnv(&d, "HOLSTER_DURATION", time.Second)
+	c.Assert(err, ErrorMatches, "HOLSTER_DURATION='15' is not a duration")
+	c.Assert(d, Equals, time.Duration(0))
+
+	var f float64
+	err = holster.SetDefaultFromEnv(&f, "HOLSTER_INT", 1.0)
+	c.Assert(err, ErrorMatches, "holster.SetDefaultFromEnv: unsupported type float64")
+}
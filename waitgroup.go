@@ -15,20 +15,56 @@ limitations under the License.
 */
 package holster
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mailgun/holster/clock"
+	"github.com/pkg/errors"
+)
 
 type WaitGroup struct {
-	wg    sync.WaitGroup
-	mutex sync.Mutex
-	errs  []error
-	done  chan struct{}
+	wg     sync.WaitGroup
+	mutex  sync.Mutex
+	errs   []error
+	done   chan struct{}
+	waitCh chan struct{}
+	// Limits the number of routines started by Run(), Go() and GoErr() running at once
+	sem chan struct{}
+}
+
+// NewLimitedWaitGroup returns a WaitGroup where at most `max` routines started by
+// `Run()`, `Go()`, `GoErr()` or `GoRecover()` run at once, further calls block until a
+// running routine completes. Loops started by `Until()` and friends are not limited.
+// A `max` less than 1 is treated as 1, such that routines run one at a time.
+func NewLimitedWaitGroup(max int) *WaitGroup {
+	if max < 1 {
+		max = 1
+	}
+	return &WaitGroup{sem: make(chan struct{}, max)}
+}
+
+// acquire blocks until a routine may be started if the WaitGroup is limited
+func (wg *WaitGroup) acquire() {
+	if wg.sem != nil {
+		wg.sem <- struct{}{}
+	}
+}
+
+func (wg *WaitGroup) release() {
+	if wg.sem != nil {
+		<-wg.sem
+	}
 }
 
 // Run a routine and collect errors if any
 func (wg *WaitGroup) Run(callBack func(interface{}) error, data interface{}) {
+	wg.acquire()
 	wg.wg.Add(1)
 	go func() {
 		err := callBack(data)
+		wg.release()
 		if err == nil {
 			wg.wg.Done()
 			return
@@ -42,13 +78,44 @@ func (wg *WaitGroup) Run(callBack func(interface{}) error, data interface{}) {
 
 // Execute a long running routine
 func (wg *WaitGroup) Go(cb func()) {
+	wg.acquire()
 	wg.wg.Add(1)
 	go func() {
 		cb()
+		wg.release()
 		wg.wg.Done()
 	}()
 }
 
+// Execute a routine and collect the error it returns if any
+func (wg *WaitGroup) GoErr(cb func() error) {
+	wg.acquire()
+	wg.wg.Add(1)
+	go func() {
+		defer wg.wg.Done()
+		defer wg.release()
+		if err := cb(); err != nil {
+			wg.mutex.Lock()
+			wg.errs = append(wg.errs, err)
+			wg.mutex.Unlock()
+		}
+	}()
+}
+
+// GoRecover is like GoErr but if the routine panics, the panic is recovered and
+// collected as an error for Wait() to return instead of crashing the process. The
+// error includes the stack trace of the panic when formatted with "%+v".
+func (wg *WaitGroup) GoRecover(cb func() error) {
+	wg.GoErr(func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = errors.Errorf("panic: %v", r)
+			}
+		}()
+		return cb()
+	})
+}
+
 // Run a goroutine in a loop continuously, if the callBack returns false the loop is broken.
 // `Until()` differs from `Loop()` in that if the `Stop()` is called on the WaitGroup
 // the `done` channel is closed. Implementations of the callBack function can listen
@@ -71,19 +138,89 @@ func (wg *WaitGroup) Until(callBack func(done chan struct{}) bool) {
 	}()
 }
 
+// UntilWithContext is like `Until()` but the loop can also be cancelled through the
+// context. The `done` channel passed to the callBack is closed and `ctx` is cancelled
+// when either `Stop()` is called or the provided context is cancelled, after which the
+// loop is broken once the callBack returns.
+func (wg *WaitGroup) UntilWithContext(ctx context.Context, callBack func(ctx context.Context, done chan struct{}) bool) {
+	wg.mutex.Lock()
+	if wg.done == nil {
+		wg.done = make(chan struct{})
+	}
+	stop := wg.done
+	wg.mutex.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+		close(done)
+	}()
+
+	wg.wg.Add(1)
+	go func() {
+		defer wg.wg.Done()
+		defer cancel()
+		for {
+			if !callBack(ctx, done) || ctx.Err() != nil {
+				break
+			}
+		}
+	}()
+}
+
 // Stop closes the done channel passed into `Until()` calls and waits for
 // the `Until()` callBack to return false.
 func (wg *WaitGroup) Stop() {
 	wg.mutex.Lock()
 	defer wg.mutex.Unlock()
 
-	if wg.done != nil {
-		close(wg.done)
-	}
+	wg.closeDone()
 	wg.wg.Wait()
 	wg.done = nil
 }
 
+// StopWithContext is like `Stop()` but gives up waiting for the routines to complete
+// and returns `ctx.Err()` once the context is done, such that shutdown can not hang
+// forever on a routine which does not return. The routines are still signaled to stop
+// and a subsequent call to `Stop()` or `Wait()` waits for them to complete.
+func (wg *WaitGroup) StopWithContext(ctx context.Context) error {
+	wg.mutex.Lock()
+	wg.closeDone()
+	done := wg.done
+	wg.mutex.Unlock()
+
+	select {
+	case <-wg.waiter():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	wg.mutex.Lock()
+	if wg.done == done {
+		wg.done = nil
+	}
+	wg.mutex.Unlock()
+	return nil
+}
+
+// closeDone closes the done channel unless a previous stop already closed it,
+// the caller must hold the mutex
+func (wg *WaitGroup) closeDone() {
+	if wg.done == nil {
+		return
+	}
+	select {
+	case <-wg.done:
+	default:
+		close(wg.done)
+	}
+}
+
 // Run a goroutine in a loop continuously, if the callBack returns false the loop is broken
 func (wg *WaitGroup) Loop(callBack func() bool) {
 	wg.wg.Add(1)
@@ -97,6 +234,35 @@ func (wg *WaitGroup) Loop(callBack func() bool) {
 	}()
 }
 
+// LoopEvery runs the callBack every interval until it returns false or `Stop()` is
+// called on the WaitGroup. Intervals are measured by the clock package, so the loop
+// can be driven by `clock.Advance()` in tests.
+func (wg *WaitGroup) LoopEvery(interval time.Duration, callBack func() bool) {
+	wg.mutex.Lock()
+	if wg.done == nil {
+		wg.done = make(chan struct{})
+	}
+	done := wg.done
+	wg.mutex.Unlock()
+
+	ticker := clock.NewTicker(interval)
+	wg.wg.Add(1)
+	go func() {
+		defer wg.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				if !callBack() {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
 // Wait for all the routines to complete and return any errors collected
 func (wg *WaitGroup) Wait() []error {
 	wg.wg.Wait()
@@ -109,3 +275,39 @@ func (wg *WaitGroup) Wait() []error {
 	}
 	return wg.errs
 }
+
+// WaitWithTimeout waits for all the routines to complete, returning true if they
+// did so within the timeout and false otherwise. Errors collected by the routines
+// are returned by a subsequent call to `Wait()`. Concurrent and repeated calls
+// share a single waiter goroutine which exits as soon as all the routines complete.
+func (wg *WaitGroup) WaitWithTimeout(timeout time.Duration) bool {
+	timer := clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-wg.waiter():
+		return true
+	case <-timer.C():
+		return false
+	}
+}
+
+// waiter returns a channel which is closed once all the routines complete. Concurrent
+// callers share a single waiter goroutine.
+func (wg *WaitGroup) waiter() <-chan struct{} {
+	wg.mutex.Lock()
+	defer wg.mutex.Unlock()
+
+	if wg.waitCh == nil {
+		waitCh := make(chan struct{})
+		wg.waitCh = waitCh
+		go func() {
+			wg.wg.Wait()
+			wg.mutex.Lock()
+			wg.waitCh = nil
+			wg.mutex.Unlock()
+			close(waitCh)
+		}()
+	}
+	return wg.waitCh
+}
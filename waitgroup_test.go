@@ -5,7 +5,7 @@ Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
 You may obtain a copy of the License at
 
-     http://www.apache.org/licenses/LICENSE-2.0
+	http://www.apache.org/licenses/LICENSE-2.0
 
 Unless required by applicable law or agreed to in writing, software
 distributed under the License is distributed on an "AS IS" BASIS,
@@ -16,11 +16,17 @@ limitations under the License.
 package holster_test
 
 import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/mailgun/holster"
+	"github.com/mailgun/holster/clock"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/ahmetb/go-linq.v3"
@@ -90,6 +96,112 @@ OUT:
 	s.Nil(errs)
 }
 
+func (s *WaitGroupTestSuite) TestGoErr() {
+	var wg holster.WaitGroup
+
+	var items []error
+	for i := 0; i < 10; i++ {
+		var err error
+		if i%2 == 0 {
+			err = errors.Errorf("Error %d", i)
+			items = append(items, err)
+		}
+		wg.GoErr(func() error {
+			// Do some long running thing
+			time.Sleep(time.Nanosecond * 50)
+			return err
+		})
+	}
+
+	errs := wg.Wait()
+	s.Equal(5, len(errs))
+	for _, item := range items {
+		s.Equal(true, linq.From(errs).Contains(item))
+	}
+}
+
+func (s *WaitGroupTestSuite) TestGoRecover() {
+	var wg holster.WaitGroup
+
+	wg.GoRecover(func() error {
+		var m map[string]int
+		m["boom"] = 1
+		return nil
+	})
+	wg.GoRecover(func() error {
+		return errors.New("returned error")
+	})
+	wg.GoRecover(func() error {
+		return nil
+	})
+
+	errs := wg.Wait()
+	s.Require().Equal(2, len(errs))
+
+	var panicErr error
+	for _, err := range errs {
+		if strings.HasPrefix(err.Error(), "panic: ") {
+			panicErr = err
+		}
+	}
+	s.Require().NotNil(panicErr)
+	s.Contains(panicErr.Error(), "assignment to entry in nil map")
+	// The stack includes the function which panicked
+	s.Contains(fmt.Sprintf("%+v", panicErr), "TestGoRecover")
+}
+
+func (s *WaitGroupTestSuite) TestLimitedWaitGroup() {
+	wg := holster.NewLimitedWaitGroup(3)
+	var mutex sync.Mutex
+	var running, max int
+
+	for i := 0; i < 20; i++ {
+		wg.Go(func() {
+			mutex.Lock()
+			running++
+			if running > max {
+				max = running
+			}
+			mutex.Unlock()
+
+			time.Sleep(time.Millisecond * 5)
+
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+		})
+	}
+	s.Nil(wg.Wait())
+	s.Equal(3, max)
+}
+
+func (s *WaitGroupTestSuite) TestLimitedWaitGroupLessThanOne() {
+	for _, limit := range []int{0, -1} {
+		wg := holster.NewLimitedWaitGroup(limit)
+		var mutex sync.Mutex
+		var running, max int
+
+		for i := 0; i < 5; i++ {
+			wg.Go(func() {
+				mutex.Lock()
+				running++
+				if running > max {
+					max = running
+				}
+				mutex.Unlock()
+
+				time.Sleep(time.Millisecond * 5)
+
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+			})
+		}
+		s.Nil(wg.Wait())
+		s.Equal(1, max)
+	}
+}
+
 func (s *WaitGroupTestSuite) TestLoop() {
 	pipe := make(chan int32, 0)
 	var wg holster.WaitGroup
@@ -153,3 +265,146 @@ func (s *WaitGroupTestSuite) TestUntil() {
 	wg.Stop()
 	s.Equal(int32(16), count)
 }
+
+func (s *WaitGroupTestSuite) TestUntilWithContext() {
+	var wg holster.WaitGroup
+	var count int32
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wg.UntilWithContext(ctx, func(ctx context.Context, done chan struct{}) bool {
+		select {
+		case <-time.After(time.Millisecond):
+			atomic.AddInt32(&count, 1)
+		case <-done:
+			return false
+		}
+		return true
+	})
+
+	// Loops that ignore `done` also terminate once the context is cancelled
+	wg.UntilWithContext(ctx, func(ctx context.Context, done chan struct{}) bool {
+		time.Sleep(time.Millisecond)
+		return true
+	})
+
+	time.Sleep(time.Millisecond * 10)
+	cancel()
+
+	exited := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		s.Fail("waited to long for UntilWithContext() to exit")
+	}
+	s.True(atomic.LoadInt32(&count) > 0)
+}
+
+func (s *WaitGroupTestSuite) TestUntilWithContextStop() {
+	var wg holster.WaitGroup
+
+	wg.UntilWithContext(context.Background(), func(ctx context.Context, done chan struct{}) bool {
+		<-ctx.Done()
+		return true
+	})
+
+	// Stop cancels the context passed to the callBack
+	wg.Stop()
+}
+
+func (s *WaitGroupTestSuite) TestStopWithContext() {
+	var wg holster.WaitGroup
+	release := make(chan struct{})
+
+	wg.Until(func(done chan struct{}) bool {
+		<-done
+		// A slow routine which does not return promptly once stopped
+		<-release
+		return false
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	s.Equal(context.DeadlineExceeded, wg.StopWithContext(ctx))
+
+	close(release)
+	s.Nil(wg.StopWithContext(context.Background()))
+	s.Nil(wg.Wait())
+}
+
+func (s *WaitGroupTestSuite) TestWaitWithTimeout() {
+	var wg holster.WaitGroup
+	release := make(chan struct{})
+
+	wg.Go(func() {
+		<-release
+	})
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		s.False(wg.WaitWithTimeout(time.Millisecond))
+	}
+	// Repeated timeouts do not leak a waiter each
+	s.True(runtime.NumGoroutine() <= before+1)
+
+	close(release)
+	s.True(wg.WaitWithTimeout(time.Second))
+}
+
+func (s *WaitGroupTestSuite) TestWaitWithTimeoutFast() {
+	var wg holster.WaitGroup
+
+	wg.Go(func() {
+		time.Sleep(time.Nanosecond * 50)
+	})
+	s.True(wg.WaitWithTimeout(time.Second))
+	s.Nil(wg.Wait())
+}
+
+func (s *WaitGroupTestSuite) TestLoopEvery() {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+	var wg holster.WaitGroup
+	calls := make(chan struct{}, 10)
+
+	wg.LoopEvery(time.Second, func() bool {
+		calls <- struct{}{}
+		return true
+	})
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Millisecond * 999)
+		s.Equal(0, len(calls))
+
+		clock.Advance(time.Millisecond)
+		select {
+		case <-calls:
+		case <-time.After(time.Second):
+			s.FailNow("waited to long for LoopEvery() to run")
+		}
+	}
+
+	wg.Stop()
+	clock.Advance(time.Second)
+	s.Equal(0, len(calls))
+}
+
+func (s *WaitGroupTestSuite) TestLoopEveryReturnsFalse() {
+	defer clock.Freeze(clock.Now()).Unfreeze()
+	var wg holster.WaitGroup
+	var count int32
+
+	wg.LoopEvery(time.Second, func() bool {
+		return atomic.AddInt32(&count, 1) < 2
+	})
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Second)
+		// Give the loop a chance to run before the next tick
+		time.Sleep(time.Millisecond * 10)
+	}
+	s.Nil(wg.Wait())
+	s.Equal(int32(2), atomic.LoadInt32(&count))
+}